@@ -1,12 +1,16 @@
 package geecache
 
-// ByteView 只有一个数据成员，b []byte，b将会存储真实的缓存值，选择byte类型是为了能够支持任意的数据类型的存储，例如字符串，图片等。
+import "time"
+
+// ByteView 的数据成员b []byte将会存储真实的缓存值，选择byte类型是为了能够支持任意的数据类型的存储，例如字符串，图片等。
+// e 是缓存值的过期时间，零值表示永不过期
 // A ByteView holds an immutable view of bytes
 type ByteView struct {
 	b []byte
+	e time.Time
 }
 
-// Len 实现Len() int方法，我们在lru.Cache的实现中，要求被缓存对象必须实现Value接口，即Len() int方法，返回其所占的内存大小（即Cache.cache是一个map，键是string，值是*list.Element，Element中的Value存放的是entry，entry这个结构体有个成员是Value类型的，这个也就是ByteView）
+// Len 实现Len() int方法，返回其所占的内存大小，ByteView因此实现了lru.Value接口；cache.go中使用的lru.TypedCache[string, ByteView]也用它计算每个条目占用的内存（即Cache.cache是一个map，键是string，值是*list.Element，Element中的Value存放的是entry，entry这个结构体有个成员value，也就是ByteView）
 // Len returns the view's length
 func (v ByteView) Len() int {
 	return len(v.b)
@@ -18,6 +22,11 @@ func (v ByteView) ByteSlice() []byte {
 	return cloneBytes(v.b)
 }
 
+// Expire returns the view's expire time, the zero time means it never expires
+func (v ByteView) Expire() time.Time {
+	return v.e
+}
+
 // String returns the data as a string, making a copy if necessary
 func (v ByteView) String() string {
 	return string(v.b)
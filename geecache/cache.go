@@ -2,35 +2,210 @@ package geecache
 
 import (
 	"LinJz_gee_cache/geecache/lru"
+	"hash/maphash"
 	"sync"
+	"sync/atomic"
 )
 
-// cache.go的实现非常简单，实例化lru，封装get和add方法，并添加互斥锁mu
+// cache 把key按哈希值分配到多个shard上，每个shard有自己的锁和lru，容量为cacheBytes/shard数
+// 因为lru的Get也会修改链表，只有一把锁时连读操作也是串行的，分片之后不同shard上的操作可以并行
+// 只有一个shard时不计算哈希值
 type cache struct {
-	mu         sync.Mutex
-	lru        *lru.Cache
+	seed   maphash.Seed
+	shards []cacheShard
+}
+
+// minShardBytes 每个shard至少分到的容量，shard太小时稍大的值刚放入就会被淘汰
+const minShardBytes = 64 << 10
+
+// newCache 创建有n个shard的cache，n小于1时按1处理
+// cacheBytes不为0时，shard数最多为cacheBytes/minShardBytes，保证每个shard至少有minShardBytes的容量
+func newCache(cacheBytes int64, policy lru.PolicyKind, n int) cache {
+	if cacheBytes > 0 {
+		n = min(n, int(cacheBytes/minShardBytes))
+	}
+	n = max(n, 1)
+	shardBytes := cacheBytes / int64(n)
+	// 所有shard使用同一种策略，这里创建一个空的实例判断一次是否支持并发查找，lookup据此直接选择读锁或写锁
+	_, sharedGet := lru.NewPolicy[string, ByteView](policy, 0, nil, nil).(lru.SharedGetter[string, ByteView])
+	c := cache{seed: maphash.MakeSeed(), shards: make([]cacheShard, n)}
+	for i := range c.shards {
+		c.shards[i].cacheBytes = shardBytes
+		c.shards[i].policy = policy
+		c.shards[i].sharedGet = sharedGet
+	}
+	return c
+}
+
+// shard 返回key所在的shard
+func (c *cache) shard(key string) *cacheShard {
+	if len(c.shards) == 1 {
+		return &c.shards[0]
+	}
+	return &c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
+func (c *cache) add(key string, value ByteView) {
+	c.shard(key).add(key, value)
+}
+
+func (c *cache) get(key string) (value ByteView, ok bool) {
+	return c.shard(key).get(key)
+}
+
+func (c *cache) remove(key string) {
+	c.shard(key).remove(key)
+}
+
+// keys 返回所有shard中没有过期的key，同一个shard中的key按照淘汰策略的顺序排列
+func (c *cache) keys() []string {
+	var keys []string
+	for i := range c.shards {
+		keys = c.shards[i].appendKeys(keys)
+	}
+	return keys
+}
+
+// purge 清空所有shard，统计数据保留
+func (c *cache) purge() {
+	for i := range c.shards {
+		c.shards[i].purge()
+	}
+}
+
+// stats 汇总所有shard的统计数据
+func (c *cache) stats() CacheStats {
+	var s CacheStats
+	for i := range c.shards {
+		ss := c.shards[i].stats()
+		s.Bytes += ss.Bytes
+		s.Items += ss.Items
+		s.Gets += ss.Gets
+		s.Hits += ss.Hits
+		s.Evictions += ss.Evictions
+	}
+	return s
+}
+
+// maxBytes 返回所有shard的容量之和
+func (c *cache) maxBytes() int64 {
+	var n int64
+	for i := range c.shards {
+		n += c.shards[i].cacheBytes
+	}
+	return n
+}
+
+// cacheShard 的实现非常简单，实例化lru，封装get和add方法，并添加读写锁mu
+// policy决定使用的淘汰策略，默认为LRU；如果lru实现了lru.SharedGetter（例如lru.Clock），sharedGet为true，shared就是lru本身，查找只需要持有读锁
+// nget、nhit、nevict分别记录查找次数、命中次数和淘汰次数，nevict由mu保护
+type cacheShard struct {
+	mu         sync.RWMutex
+	lru        lru.Policy[string, ByteView]
+	shared     lru.SharedGetter[string, ByteView]
+	sharedGet  bool
+	policy     lru.PolicyKind
 	cacheBytes int64
+	nget       atomic.Int64
+	nhit       atomic.Int64
+	nevict     int64 // number of capacity and expiry evictions
+}
+
+// CacheStats 是某个cache统计数据的快照
+// CacheStats are returned by stats accessors on Group
+type CacheStats struct {
+	Bytes     int64
+	Items     int64
+	Gets      int64
+	Hits      int64
+	Evictions int64
+}
+
+func (c *cacheShard) stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s := CacheStats{
+		Gets:      c.nget.Load(),
+		Hits:      c.nhit.Load(),
+		Evictions: c.nevict,
+	}
+	if c.lru != nil {
+		s.Bytes = c.lru.Bytes()
+		s.Items = int64(c.lru.Len())
+	}
+	return s
 }
 
 // 在add方法中，判断了c.lru是否为nil，如果等于nil再创建实例，这种方法称之为延迟初始化（Lazy Initialization），也叫做懒汉式，一个对象的延迟初始化意味着该对象的创建将会延迟至第一次使用该对象时，主要用于提高性能，并减少程序内存要求
-func (c *cache) add(key string, value ByteView) {
+func (c *cacheShard) add(key string, value ByteView) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil)
+		c.lru = lru.NewPolicy(c.policy, c.cacheBytes, func(key string, value ByteView) int64 {
+			return int64(len(key) + value.Len())
+		}, func(key string, value ByteView, reason lru.EvictReason) {
+			if reason != lru.EvictRemoved {
+				c.nevict++
+			}
+		})
+		c.shared, _ = c.lru.(lru.SharedGetter[string, ByteView])
 	}
-	c.lru.Add(key, value)
+	c.lru.AddWithExpire(key, value, value.Expire())
 }
 
-func (c *cache) get(key string) (value ByteView, ok bool) {
+func (c *cacheShard) get(key string) (value ByteView, ok bool) {
+	c.nget.Add(1)
+	if value, ok = c.lookup(key); ok {
+		c.nhit.Add(1)
+	}
+	return
+}
+
+// lookup 支持并发查找的lru在读锁下查找，其余的lru在Get时会调整顺序，直接持有写锁
+func (c *cacheShard) lookup(key string) (value ByteView, ok bool) {
+	if c.sharedGet {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		if c.shared == nil {
+			return
+		}
+		return c.shared.GetShared(key)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.lru == nil {
 		return
 	}
+	return c.lru.Get(key)
+}
 
-	if v, ok := c.lru.Get(key); ok {
-		return v.(ByteView), ok // v.(ByteView)是类型转换
+func (c *cacheShard) appendKeys(keys []string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lru == nil {
+		return keys
 	}
-	return
+	c.lru.Range(func(key string, value ByteView) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// purge 丢弃整个lru，下次add时重新创建，被丢弃的条目不计入淘汰次数
+func (c *cacheShard) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru = nil
+	c.shared = nil
+}
+
+func (c *cacheShard) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		return
+	}
+	c.lru.Remove(key)
 }
@@ -0,0 +1,142 @@
+package geecache
+
+import (
+	"LinJz_gee_cache/geecache/lru"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCacheShards(t *testing.T) {
+	c := newCache(1<<20, lru.LRU, 8)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		c.add(key, ByteView{b: []byte(key)})
+	}
+	for i := range c.shards {
+		if n := c.shards[i].stats().Items; n == 0 || n > 250 {
+			t.Errorf("shard %d has %d items, keys are not spread evenly", i, n)
+		}
+	}
+	if v, ok := c.get("42"); !ok || v.String() != "42" {
+		t.Fatalf("get(42) = %v, %v", v, ok)
+	}
+	c.remove("42")
+	if _, ok := c.get("42"); ok {
+		t.Fatal("42 should be removed")
+	}
+	if s := c.stats(); s.Items != 999 || s.Gets != 2 || s.Hits != 1 || c.maxBytes() != 1<<20 {
+		t.Fatalf("stats() = %+v, maxBytes() = %d", s, c.maxBytes())
+	}
+}
+
+// shard数受minShardBytes限制，0表示不限制内存，不受影响
+func TestCacheShardsMinBytes(t *testing.T) {
+	for _, tc := range []struct {
+		cacheBytes int64
+		n, shards  int
+	}{
+		{1 << 20, 8, 8},
+		{4 * minShardBytes, 16, 4},
+		{minShardBytes - 1, 8, 1},
+		{0, 8, 8},
+	} {
+		if c := newCache(tc.cacheBytes, lru.LRU, tc.n); len(c.shards) != tc.shards {
+			t.Errorf("newCache(%d, %d) has %d shards, expect %d", tc.cacheBytes, tc.n, len(c.shards), tc.shards)
+		}
+	}
+}
+
+// BenchmarkCacheParallel 比较不同淘汰策略和shard数下并发读写的吞吐量，90%为读，10%为写
+// lru.Clock的查找只需要读锁
+func BenchmarkCacheParallel(b *testing.B) {
+	const keys = 1 << 14
+	views := make([]ByteView, keys)
+	names := make([]string, keys)
+	for i := range views {
+		names[i] = strconv.Itoa(i)
+		views[i] = ByteView{b: []byte(names[i])}
+	}
+	for _, bc := range []struct {
+		policy lru.PolicyKind
+		shards int
+	}{{lru.LRU, 1}, {lru.LRU, 4}, {lru.LRU, 16}, {lru.LRU, 64}, {lru.Clock, 1}, {lru.Clock, 16}} {
+		b.Run(fmt.Sprintf("%s/shards=%d", bc.policy, bc.shards), func(b *testing.B) {
+			c := newCache(1<<30, bc.policy, bc.shards)
+			for i := range names {
+				c.add(names[i], views[i])
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					k := (i * 7919) % keys
+					if i%10 == 0 {
+						c.add(names[k], views[k])
+					} else {
+						c.get(names[k])
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+// 使用lru.Clock时，查找在读锁下进行，并发查找与写入交错时统计数据仍然准确
+func TestCacheSharedGet(t *testing.T) {
+	c := newCache(1<<20, lru.Clock, 1)
+	c.add("Tom", ByteView{b: []byte("630")})
+	if !c.shards[0].sharedGet || c.shards[0].shared == nil {
+		t.Fatal("clock cache should support shared lookups")
+	}
+	if newCache(1<<20, lru.LRU, 1).shards[0].sharedGet {
+		t.Fatal("lru cache should not support shared lookups")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if v, ok := c.get("Tom"); !ok || v.String() != "630" {
+					t.Errorf("get(Tom) = %v, %v", v, ok)
+				}
+				c.add(strconv.Itoa(i*100+j), ByteView{b: []byte("x")})
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s := c.stats(); s.Gets != 800 || s.Hits != 800 || s.Items != 801 {
+		t.Fatalf("stats() = %+v", s)
+	}
+}
+
+// purge可能与lookup并发执行，配合-race运行
+func TestCacheGetPurge(t *testing.T) {
+	for _, policy := range []lru.PolicyKind{lru.LRU, lru.Clock} {
+		c := newCache(1<<20, policy, 2)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					key := strconv.Itoa(i % 16)
+					c.add(key, ByteView{b: []byte(key)})
+					c.get(key)
+					c.remove(key)
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				c.purge()
+			}
+		}()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,99 @@
+package consistenthash
+
+import (
+	"math"
+	"sort"
+)
+
+// 有界负载的一致性哈希（Consistent Hashing with Bounded Loads, Mirrokni et al.）
+// 普通的一致性哈希在key分布不均匀时，某个节点可能承担远超平均值的请求
+// 有界负载模式记录每个节点正在处理的请求数（负载），每个节点的负载上限是平均负载的(1+epsilon)倍，
+// 查找时如果key的所属节点已经达到上限，就沿着环顺时针找到第一个没有达到上限的节点
+// 调用者在请求开始时调用Inc，结束时调用Done
+
+// defaultEpsilon 默认允许节点的负载超过平均值25%
+const defaultEpsilon = 0.25
+
+// SetEpsilon 设置有界负载模式中允许超过平均负载的比例，epsilon越小负载越均衡，但key离开所属节点的概率越大
+// SetEpsilon sets how far above the average load a node may go
+func (m *Map) SetEpsilon(epsilon float64) {
+	m.epsilon = epsilon
+}
+
+// SetLocal 设置本节点的名称。本节点自己的加载不经过Inc/Done，负载无从统计，
+// 所以本节点永远不算超载，其余节点的负载上限只按它们之间的虚拟节点数分配，否则远程节点会一直被判定为超载
+// SetLocal names the node whose load is not tracked, it is left out of the bounds
+func (m *Map) SetLocal(node string) {
+	m.local = node
+}
+
+// GetLeast 与Get类似，但会跳过负载已经达到上限的节点
+// GetLeast gets the closest item in the hash to the provided key whose load is within bounds
+func (m *Map) GetLeast(key string) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	for i := 0; i < len(m.keys); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !m.Overloaded(node) {
+			return node
+		}
+	}
+	// 负载上限总是大于平均值，不可能所有节点都超过上限，这里只是兜底
+	return m.hashMap[m.keys[idx%len(m.keys)]]
+}
+
+// Overloaded 返回再给node分配一个请求是否会超过它的负载上限
+// 负载上限是ceil((1+epsilon) * (totalLoad+1) * node的虚拟节点数 / 虚拟节点总数)，对于没有权重的环就是平均负载的(1+epsilon)倍
+// 虚拟节点总数不包括SetLocal设置的本节点
+// Overloaded reports whether one more request would push node over its bound
+func (m *Map) Overloaded(node string) bool {
+	vnodes, ok := m.nodes[node]
+	if !ok || node == m.local {
+		return false
+	}
+	total := len(m.keys)
+	if m.local != "" {
+		total -= m.nodes[m.local]
+	}
+	if total <= 0 {
+		return false
+	}
+	share := float64(vnodes) / float64(total)
+	bound := math.Ceil((1 + m.epsilon) * float64(m.totalLoad+1) * share)
+	return float64(m.loads[node]+1) > bound
+}
+
+// Inc 增加node的负载，在向node发出请求时调用
+// Inc records a request sent to node
+func (m *Map) Inc(node string) {
+	if _, ok := m.nodes[node]; !ok {
+		return
+	}
+	m.loads[node]++
+	m.totalLoad++
+}
+
+// Done 减少node的负载，在发往node的请求结束时调用
+// Done records the end of a request sent to node
+func (m *Map) Done(node string) {
+	if m.loads[node] <= 0 {
+		return
+	}
+	m.loads[node]--
+	m.totalLoad--
+}
+
+// Loads 返回每个节点当前的负载
+// Loads returns the current load of every node
+func (m *Map) Loads() map[string]int64 {
+	loads := make(map[string]int64, len(m.loads))
+	for node, load := range m.loads {
+		loads[node] = load
+	}
+	return loads
+}
@@ -2,6 +2,7 @@ package consistenthash
 
 import (
 	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
 )
@@ -10,13 +11,20 @@ import (
 // Hash maps bytes to uint32
 type Hash func(data []byte) uint32
 
-// Map 是一致性哈希算法的主数据结构，包含4个成员变量：Hash函数hash;虚拟节点倍数replicas；哈希环keys；虚拟节点与真实节点的映射表hashMap，键是虚拟节点的哈希值，值是真实节点的名称
+// Map 是一致性哈希算法的主数据结构，包含6个成员变量：Hash函数hash;虚拟节点倍数replicas；哈希环keys；虚拟节点与真实节点的映射表hashMap，键是虚拟节点的哈希值，值是真实节点的名称；真实节点及其虚拟节点个数nodes；发生碰撞的虚拟节点collided
 // Map contains all hashed keys
 type Map struct {
 	hash     Hash
 	replicas int
-	keys     []int // Sorted
+	keys     []int // Sorted, no duplicates
 	hashMap  map[int]string
+	nodes    map[string]int   // node -> number of virtual nodes
+	collided map[int][]string // hash -> all nodes claiming it, sorted
+	// 有界负载模式使用，见bounded.go
+	epsilon   float64
+	loads     map[string]int64
+	totalLoad int64
+	local     string
 }
 
 // New 构造函数New()允许自定义虚拟节点倍数和Hash函数
@@ -26,6 +34,10 @@ func New(replicas int, fn Hash) *Map {
 		replicas: replicas,
 		hash:     fn,
 		hashMap:  make(map[int]string),
+		nodes:    make(map[string]int),
+		collided: make(map[int][]string),
+		epsilon:  defaultEpsilon,
+		loads:    make(map[string]int64),
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE
@@ -37,16 +49,108 @@ func New(replicas int, fn Hash) *Map {
 // 对每一个真实节点key，对应创建m.replicas个虚拟节点，虚拟节点的名称是strconv.Itoa(i) + key，即通过添加编号的方式区分不同虚拟节点
 // 使用m.hash()计算虚拟节点的哈希值，使用append(m.keys，hash)添加到环上，在hashMap中增加虚拟节点和真实节点的映射关系
 // 最后一步，环上的哈希值排序
+// 已经在环上的节点会被忽略
 // Add adds some keys to the hash
 func (m *Map) Add(keys ...string) {
 	for _, key := range keys {
-		for i := 0; i < m.replicas; i++ {
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		m.add(key, m.replicas)
+	}
+	sort.Ints(m.keys)
+}
+
+// AddWeighted 添加一个带权重的真实节点，它的虚拟节点个数为replicas*weight（至少为1），所以它负责的key的比例与weight成正比
+// 例如可以按照各个节点的内存大小设置weight，如果节点已经在环上且权重不同，则按新的权重重新添加
+// AddWeighted adds a key whose share of the hash is proportional to weight
+func (m *Map) AddWeighted(key string, weight float64) {
+	vnodes := int(math.Round(float64(m.replicas) * weight))
+	if vnodes < 1 {
+		vnodes = 1
+	}
+	if n, ok := m.nodes[key]; ok && n != vnodes {
+		m.Remove(key)
+	}
+	m.add(key, vnodes)
+	sort.Ints(m.keys)
+}
+
+// add 为真实节点key创建vnodes个虚拟节点，调用者需要在之后对m.keys排序
+// 如果虚拟节点的哈希值与环上已有的虚拟节点相同（碰撞），这个位置归名称最小的节点所有，与添加的顺序无关
+// 所有声明过这个位置的节点都记录在collided中，归属的节点被删除后，位置交给下一个节点
+func (m *Map) add(key string, vnodes int) {
+	if _, ok := m.nodes[key]; ok {
+		return
+	}
+	m.nodes[key] = vnodes
+	for i := 0; i < vnodes; i++ {
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		owner, ok := m.hashMap[hash]
+		if !ok {
 			m.keys = append(m.keys, hash)
 			m.hashMap[hash] = key
+			continue
+		}
+		claimants := m.collided[hash]
+		if claimants == nil {
+			claimants = []string{owner}
 		}
+		claimants = append(claimants, key)
+		sort.Strings(claimants)
+		m.collided[hash] = claimants
+		m.hashMap[hash] = claimants[0]
 	}
-	sort.Ints(m.keys)
+}
+
+// Remove 从环上删除真实节点及其所有虚拟节点，原来属于这些节点的key会落到顺时针方向的下一个节点上，其余key的归属不变
+// Remove removes some keys from the hash
+func (m *Map) Remove(keys ...string) {
+	removed := false
+	for _, key := range keys {
+		if _, ok := m.nodes[key]; ok {
+			delete(m.nodes, key)
+			m.totalLoad -= m.loads[key]
+			delete(m.loads, key)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	for hash, claimants := range m.collided {
+		alive := make([]string, 0, len(claimants))
+		for _, node := range claimants {
+			if _, ok := m.nodes[node]; ok {
+				alive = append(alive, node)
+			}
+		}
+		if len(alive) > 0 {
+			m.hashMap[hash] = alive[0]
+		}
+		if len(alive) > 1 {
+			m.collided[hash] = alive
+		} else {
+			delete(m.collided, hash)
+		}
+	}
+	kept := m.keys[:0] // 原地过滤，保持有序
+	for _, hash := range m.keys {
+		if _, ok := m.nodes[m.hashMap[hash]]; ok {
+			kept = append(kept, hash)
+		} else {
+			delete(m.hashMap, hash)
+		}
+	}
+	m.keys = kept
+}
+
+// Nodes 按名称顺序返回环上所有的真实节点
+// Nodes returns the items in the hash, sorted by name
+func (m *Map) Nodes() []string {
+	nodes := make([]string, 0, len(m.nodes))
+	for node := range m.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
 }
 
 // Get 第一步，计算key的哈希值
@@ -64,3 +168,26 @@ func (m *Map) Get(key string) string {
 	})
 	return m.hashMap[m.keys[idx%len(m.keys)]] // m.keys是一个[]int，idx是一个int类型的数组下标，m.keys[idx%len(m.keys)]是一个int类型的hash值，然后m.hashMap[]是一个map[int]string，返回一个string类型的节点key
 }
+
+// GetN 与Get类似，从key的哈希值开始顺时针查找，返回最多n个不同的真实节点，第一个就是Get返回的节点，后面的依次是它在环上的后继节点
+// 当第一个节点不可用时，调用者可以依次尝试后面的节点
+// GetN returns up to n distinct items in ring order starting at the owner of key
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
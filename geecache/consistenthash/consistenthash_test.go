@@ -1,6 +1,8 @@
 package consistenthash
 
 import (
+	"math"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -46,3 +48,226 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+// 使用与TestHashing相同的哈希函数，虚拟节点为2,4,6,12,14,16,22,24,26
+func TestGetN(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2")
+
+	testCases := []struct {
+		key    string
+		n      int
+		expect []string
+	}{
+		{"11", 1, []string{"2"}},
+		{"11", 2, []string{"2", "4"}},
+		{"23", 3, []string{"4", "6", "2"}},
+		{"27", 5, []string{"2", "4", "6"}}, // 只有3个真实节点
+		{"27", 0, nil},
+	}
+	for _, c := range testCases {
+		if nodes := hash.GetN(c.key, c.n); !reflect.DeepEqual(nodes, c.expect) {
+			t.Errorf("GetN(%s, %d) = %v, expect %v", c.key, c.n, nodes, c.expect)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2", "8")
+	hash.Remove("8", "10")
+
+	// 删除8之后，27重新回到2，其余key不受影响
+	testCases := map[string]string{
+		"2":  "2",
+		"11": "2",
+		"23": "4",
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s,should have yielded %s", k, v)
+		}
+	}
+	if nodes := hash.Nodes(); !reflect.DeepEqual(nodes, []string{"2", "4", "6"}) {
+		t.Errorf("Nodes() = %v", nodes)
+	}
+
+	hash.Remove("2", "4", "6")
+	if hash.Get("27") != "" || len(hash.Nodes()) != 0 {
+		t.Errorf("empty ring should yield nothing")
+	}
+}
+
+// 测试带权重的节点负责的key的比例与权重成正比
+func TestAddWeighted(t *testing.T) {
+	hash := New(50, nil)
+	hash.AddWeighted("a", 1)
+	hash.AddWeighted("b", 3)
+
+	const n = 100000
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[hash.Get("key"+strconv.Itoa(i))]++
+	}
+	if share := float64(counts["b"]) / n; math.Abs(share-0.75) > 0.1 {
+		t.Errorf("b owns %.2f of the keys, expect about 0.75", share)
+	}
+
+	// 修改权重
+	hash.AddWeighted("b", 1)
+	if len(hash.keys) != 100 {
+		t.Errorf("ring has %d virtual nodes after reweighting, expect 100", len(hash.keys))
+	}
+}
+
+// 使用与TestHashing相同的哈希函数，虚拟节点为2,4,6,12,14,16,22,24,26
+func TestGetLeast(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2")
+	hash.SetEpsilon(0)
+
+	if node := hash.GetLeast("11"); node != "2" {
+		t.Fatalf("GetLeast(11) = %s, expect 2", node)
+	}
+	// 2的负载达到平均值的上限后，11落到2的下一个节点4上
+	hash.Inc("2")
+	if node := hash.GetLeast("11"); node != "4" {
+		t.Fatalf("GetLeast(11) = %s after loading 2, expect 4", node)
+	}
+	hash.Inc("4")
+	hash.Inc("6")
+	if node := hash.GetLeast("11"); node != "2" {
+		t.Fatalf("GetLeast(11) = %s with balanced loads, expect 2", node)
+	}
+
+	hash.Done("2")
+	hash.Done("4")
+	hash.Done("6")
+	hash.Done("6") // 多余的Done被忽略
+	if loads := hash.Loads(); loads["2"] != 0 || loads["6"] != 0 || hash.totalLoad != 0 {
+		t.Fatalf("Loads() = %v after Done", loads)
+	}
+}
+
+// 使用与TestHashing相同的哈希函数，节点2的虚拟节点"12"与节点12的虚拟节点"012"碰撞
+func TestCollision(t *testing.T) {
+	newMap := func() *Map {
+		return New(3, func(key []byte) uint32 {
+			i, _ := strconv.Atoi(string(key))
+			return uint32(i)
+		})
+	}
+	a, b := newMap(), newMap()
+	a.Add("2", "12")
+	b.Add("12", "2")
+	for _, m := range []*Map{a, b} {
+		if node := m.Get("11"); node != "12" {
+			t.Fatalf("Get(11) = %s, expect 12 regardless of insertion order", node)
+		}
+		if len(m.keys) != 5 {
+			t.Fatalf("ring has %d points, expect 5", len(m.keys))
+		}
+		d := m.Diagnostics()
+		if !reflect.DeepEqual(d.Collisions, []Collision{{Hash: 12, Nodes: []string{"12", "2"}}}) {
+			t.Fatalf("Collisions = %v", d.Collisions)
+		}
+	}
+
+	// 删除所有者之后，位置交给另一个节点
+	a.Remove("12")
+	if node := a.Get("11"); node != "2" {
+		t.Fatalf("Get(11) = %s after removing 12, expect 2", node)
+	}
+	if d := a.Diagnostics(); len(d.Collisions) != 0 || d.VirtualNodes != 3 {
+		t.Fatalf("Diagnostics() = %+v after removing 12", d)
+	}
+	b.Remove("2")
+	if node := b.Get("11"); node != "12" || len(b.keys) != 3 {
+		t.Fatalf("Get(11) = %s with %d points after removing 2", node, len(b.keys))
+	}
+}
+
+func TestDiagnosticsKeyspace(t *testing.T) {
+	hash := New(50, nil)
+	hash.Add("a", "b", "c", "d")
+	var total float64
+	for node, pct := range hash.Diagnostics().Keyspace {
+		if pct <= 0 {
+			t.Errorf("%s owns %.2f%% of the keyspace", node, pct)
+		}
+		total += pct
+	}
+	if math.Abs(total-100) > 1e-9 {
+		t.Fatalf("keyspace adds up to %v%%, expect 100%%", total)
+	}
+
+	// 哈希值为2,4,6,12,14,16,22,24,26，节点2负责(26, 2]、(6, 12]、(16, 22]
+	hash = New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2")
+	arcs := hash.keyspace()
+	if arcs["2"] != ringSize-26+2+6+6 || arcs["4"] != 6 || arcs["6"] != 6 {
+		t.Fatalf("keyspace() = %v", arcs)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4")
+	hash.AddWeighted("2", 2)
+	shares := hash.Distribution()
+	if len(shares) != 3 || shares[0].Node != "2" || shares[0].Replicas != 6 || shares[2].Node != "6" || shares[2].Replicas != 3 {
+		t.Fatalf("Distribution() = %+v", shares)
+	}
+	var total float64
+	for _, share := range shares {
+		total += share.Share
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Fatalf("shares add up to %v, expect 1", total)
+	}
+}
+
+func TestHashTag(t *testing.T) {
+	tests := map[string]string{
+		"user:{42}:profile": "42",
+		"user:{42}:scores":  "42",
+		"{a}{b}":            "a",
+		"foo{}{bar}":        "foo{}{bar}",
+		"foo{{bar}}":        "{bar",
+		"foo{bar":           "foo{bar",
+		"plain":             "plain",
+	}
+	for key, want := range tests {
+		if got := HashTag(key); got != want {
+			t.Errorf("HashTag(%q) = %q, expect %q", key, got, want)
+		}
+	}
+
+	hash := New(50, nil)
+	hash.Add("a", "b", "c", "d", "e")
+	for i := 0; i < 100; i++ {
+		tag := "{" + strconv.Itoa(i) + "}"
+		if hash.Get(HashTag("user:"+tag+":profile")) != hash.Get(HashTag("user:"+tag+":scores")) {
+			t.Fatalf("keys with hash tag %s are on different nodes", tag)
+		}
+		if !reflect.DeepEqual(hash.GetN(HashTag("x"+tag), 3), hash.GetN(HashTag(tag+"y"), 3)) {
+			t.Fatalf("GetN differs for keys with hash tag %s", tag)
+		}
+	}
+}
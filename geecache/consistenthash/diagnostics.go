@@ -0,0 +1,85 @@
+package consistenthash
+
+import "sort"
+
+// ringSize 是哈希环的大小，即uint32的取值个数
+const ringSize = 1 << 32
+
+// Collision 表示多个虚拟节点落在了环上的同一个位置，Nodes按名称排序，Nodes[0]是这个位置的所有者
+// 同一个节点的两个虚拟节点碰撞时，这个节点会在Nodes中出现两次
+// Collision describes virtual nodes that hashed to the same point
+type Collision struct {
+	Hash  uint32
+	Nodes []string
+}
+
+// Diagnostics 是哈希环的诊断信息
+// Diagnostics reports how the ring is laid out
+type Diagnostics struct {
+	// VirtualNodes 环上不同位置的个数
+	VirtualNodes int
+	// Collisions 所有发生碰撞的位置，按Hash排序
+	Collisions []Collision
+	// Keyspace 每个真实节点负责的哈希空间的百分比，总和为100
+	Keyspace map[string]float64
+}
+
+// Diagnostics 返回哈希环的诊断信息，可以用来检查虚拟节点倍数是否足够使key分布均匀
+// Diagnostics returns collisions and the keyspace share of each node
+func (m *Map) Diagnostics() Diagnostics {
+	d := Diagnostics{
+		VirtualNodes: len(m.keys),
+		Keyspace:     make(map[string]float64, len(m.nodes)),
+	}
+	for hash, nodes := range m.collided {
+		d.Collisions = append(d.Collisions, Collision{Hash: uint32(hash), Nodes: append([]string(nil), nodes...)})
+	}
+	sort.Slice(d.Collisions, func(i, j int) bool {
+		return d.Collisions[i].Hash < d.Collisions[j].Hash
+	})
+	for _, share := range m.Distribution() {
+		d.Keyspace[share.Node] = share.Share * 100
+	}
+	return d
+}
+
+// keyspace 返回每个真实节点负责的哈希值个数
+// 哈希值h由第一个大于等于h的虚拟节点负责，所以虚拟节点keys[i]负责(keys[i-1], keys[i]]，keys[0]还负责最后一个虚拟节点之后的部分
+func (m *Map) keyspace() map[string]int64 {
+	arcs := make(map[string]int64, len(m.nodes))
+	for node := range m.nodes {
+		arcs[node] = 0
+	}
+	for i, hash := range m.keys {
+		prev := int64(m.keys[len(m.keys)-1]) - ringSize
+		if i > 0 {
+			prev = int64(m.keys[i-1])
+		}
+		arcs[m.hashMap[hash]] += int64(hash) - prev
+	}
+	return arcs
+}
+
+// NodeShare 是一个真实节点在哈希环上的分布情况
+// NodeShare describes how much of the ring a node owns
+type NodeShare struct {
+	Node     string
+	Replicas int     // 虚拟节点个数
+	Share    float64 // 负责的哈希空间占整个32位哈希环的比例，范围是[0, 1]
+}
+
+// Distribution 按名称顺序返回每个真实节点的虚拟节点个数和负责的哈希空间比例
+// 如果key的哈希值是均匀分布的，Share就是节点将要负责的key的比例，可以用来比较不同的虚拟节点倍数和哈希函数
+// Distribution returns the share of the ring owned by each node, sorted by name
+func (m *Map) Distribution() []NodeShare {
+	arcs := m.keyspace()
+	shares := make([]NodeShare, 0, len(m.nodes))
+	for _, node := range m.Nodes() {
+		shares = append(shares, NodeShare{
+			Node:     node,
+			Replicas: m.nodes[node],
+			Share:    float64(arcs[node]) / ringSize,
+		})
+	}
+	return shares
+}
@@ -0,0 +1,19 @@
+package consistenthash
+
+import "strings"
+
+// HashTag 实现了Redis Cluster风格的哈希标签：如果key中包含"{...}"，并且花括号之间不为空，则只返回第一对花括号之间的部分，否则返回整个key
+// 这样"user:{42}:profile"和"user:{42}:scores"都只按"42"计算哈希值，一定落在同一个节点上，批量查询时只需要访问一个节点
+// Picker本身不处理哈希标签，需要的调用者（例如HTTPPool和GRPCPool）在查找前对key调用HashTag
+// HashTag returns the part of key that should be hashed
+func HashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 { // 没有右花括号或者花括号之间为空
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
@@ -0,0 +1,95 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"sort"
+)
+
+// JumpMap 实现了跳跃一致性哈希（Jump Consistent Hash, Lamping & Veach）
+// jumpHash把key映射到[0, 节点数)之间的一个编号，节点数从n变成n+1时，只有1/(n+1)的key会移动到新的编号上
+// 它不需要额外的内存，分布也非常均匀，但编号与节点按添加的顺序对应，所以所有节点必须以相同的顺序添加节点
+// 新节点总是追加在末尾，不会改变已有节点的编号；删除末尾之外的节点时，后面的节点编号会前移，移动的key比哈希环更多
+// JumpMap places keys using jump consistent hash
+type JumpMap struct {
+	hash  Hash
+	nodes []string // in insertion order
+}
+
+// NewJump creates a JumpMap instance
+func NewJump(fn Hash) *JumpMap {
+	m := &JumpMap{hash: fn}
+	if m.hash == nil {
+		m.hash = crc32.ChecksumIEEE
+	}
+	return m
+}
+
+// Add appends some nodes
+func (m *JumpMap) Add(nodes ...string) {
+	for _, node := range nodes {
+		if m.index(node) < 0 {
+			m.nodes = append(m.nodes, node)
+		}
+	}
+}
+
+// Remove removes some nodes, keeping the order of the others
+func (m *JumpMap) Remove(nodes ...string) {
+	for _, node := range nodes {
+		if i := m.index(node); i >= 0 {
+			m.nodes = append(m.nodes[:i], m.nodes[i+1:]...)
+		}
+	}
+}
+
+func (m *JumpMap) index(node string) int {
+	for i, n := range m.nodes {
+		if n == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// Nodes returns the nodes sorted by name
+func (m *JumpMap) Nodes() []string {
+	nodes := append([]string(nil), m.nodes...)
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Get returns the node owning key
+func (m *JumpMap) Get(key string) string {
+	if len(m.nodes) == 0 {
+		return ""
+	}
+	return m.nodes[jumpHash(mix64(uint64(m.hash([]byte(key)))), len(m.nodes))]
+}
+
+// GetN 返回key所属的节点，以及按编号顺序排在它后面的节点
+// GetN returns the owner of key followed by the nodes after it
+func (m *JumpMap) GetN(key string, n int) []string {
+	if len(m.nodes) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(m.nodes) {
+		n = len(m.nodes)
+	}
+	b := jumpHash(mix64(uint64(m.hash([]byte(key)))), len(m.nodes))
+	nodes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, m.nodes[(b+i)%len(m.nodes)])
+	}
+	return nodes
+}
+
+// jumpHash 是论文中的算法，返回key在buckets个编号中的编号
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
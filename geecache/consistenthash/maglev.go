@@ -0,0 +1,132 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"sort"
+)
+
+// defaultMaglevSize 查找表的大小，必须是质数，论文建议至少是节点数的100倍
+const defaultMaglevSize = 65537
+
+// MaglevMap 实现了Maglev一致性哈希（Eisenbud et al., NSDI 2016）
+// 每个节点根据自己的哈希值生成一个查找表位置的排列，节点轮流按各自的排列填充查找表中空着的位置，直到填满
+// 这样每个节点在查找表中占有的位置数几乎相等，Get只需要一次取模和一次数组访问
+// 增删节点时需要重建查找表，少量key会在其余节点之间移动
+// MaglevMap places keys using Maglev hashing
+type MaglevMap struct {
+	hash  Hash
+	size  uint64
+	nodes []string // Sorted
+	table []int    // slot -> index in nodes
+}
+
+// NewMaglev creates a MaglevMap instance
+func NewMaglev(fn Hash) *MaglevMap {
+	m := &MaglevMap{hash: fn, size: defaultMaglevSize}
+	if m.hash == nil {
+		m.hash = crc32.ChecksumIEEE
+	}
+	return m
+}
+
+// Add adds some nodes and rebuilds the lookup table
+func (m *MaglevMap) Add(nodes ...string) {
+	added := false
+	for _, node := range nodes {
+		if i := sort.SearchStrings(m.nodes, node); i == len(m.nodes) || m.nodes[i] != node {
+			m.nodes = append(m.nodes, node)
+			sort.Strings(m.nodes)
+			added = true
+		}
+	}
+	if added {
+		m.populate()
+	}
+}
+
+// Remove removes some nodes and rebuilds the lookup table
+func (m *MaglevMap) Remove(nodes ...string) {
+	removed := false
+	for _, node := range nodes {
+		if i := sort.SearchStrings(m.nodes, node); i < len(m.nodes) && m.nodes[i] == node {
+			m.nodes = append(m.nodes[:i], m.nodes[i+1:]...)
+			removed = true
+		}
+	}
+	if removed {
+		m.populate()
+	}
+}
+
+// populate 重建查找表
+// 节点i的排列为 (offset + j*skip) % size，skip与size互质（size是质数），所以排列会遍历所有位置
+func (m *MaglevMap) populate() {
+	if len(m.nodes) == 0 {
+		m.table = nil
+		return
+	}
+	offsets := make([]uint64, len(m.nodes))
+	skips := make([]uint64, len(m.nodes))
+	for i, node := range m.nodes {
+		h := mix64(uint64(m.hash([]byte(node))))
+		offsets[i] = h % m.size
+		skips[i] = mix64(h)%(m.size-1) + 1
+	}
+	table := make([]int, m.size)
+	for i := range table {
+		table[i] = -1
+	}
+	next := make([]uint64, len(m.nodes))
+	for filled := uint64(0); ; {
+		for i := range m.nodes {
+			c := (offsets[i] + next[i]*skips[i]) % m.size
+			for table[c] >= 0 {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % m.size
+			}
+			table[c] = i
+			next[i]++
+			if filled++; filled == m.size {
+				m.table = table
+				return
+			}
+		}
+	}
+}
+
+// Nodes returns the nodes sorted by name
+func (m *MaglevMap) Nodes() []string {
+	return append([]string(nil), m.nodes...)
+}
+
+// Get returns the node owning key
+func (m *MaglevMap) Get(key string) string {
+	if len(m.table) == 0 {
+		return ""
+	}
+	return m.nodes[m.table[m.slot(key)]]
+}
+
+// GetN 从key在查找表中的位置开始向后查找，返回最多n个不同的节点
+// GetN returns up to n distinct nodes following the slot of key
+func (m *MaglevMap) GetN(key string, n int) []string {
+	if len(m.table) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(m.nodes) {
+		n = len(m.nodes)
+	}
+	nodes := make([]string, 0, n)
+	seen := make(map[int]bool, n)
+	for s := m.slot(key); len(nodes) < n; s = (s + 1) % m.size {
+		if i := m.table[s]; !seen[i] {
+			seen[i] = true
+			nodes = append(nodes, m.nodes[i])
+		}
+	}
+	return nodes
+}
+
+func (m *MaglevMap) slot(key string) uint64 {
+	return mix64(uint64(m.hash([]byte(key)))) % m.size
+}
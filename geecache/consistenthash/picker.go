@@ -0,0 +1,86 @@
+package consistenthash
+
+// Picker 是key放置算法的抽象，决定每个key由哪个节点负责
+// 除了哈希环Map之外，还提供了Rendezvous（最高随机权重）、Jump（跳跃一致性哈希）和Maglev三种实现，它们不需要虚拟节点，
+// 分布更均匀，内存占用也更少，可以通过NewPicker按Placement创建
+// Picker places keys onto a set of nodes
+type Picker interface {
+	// Add 添加节点，已经存在的节点会被忽略
+	Add(nodes ...string)
+	// Remove 删除节点，原来属于这些节点的key会分给其余节点
+	Remove(nodes ...string)
+	// Nodes 按名称顺序返回所有节点
+	Nodes() []string
+	// Get 返回key所属的节点，没有节点时返回""
+	Get(key string) string
+	// GetN 返回最多n个不同的节点，第一个就是Get返回的节点，后面的是所属节点不可用时依次尝试的候选节点
+	GetN(key string, n int) []string
+}
+
+// WeightedPicker 是支持按权重分配key的Picker
+// WeightedPicker is a Picker whose nodes can own a share of keys proportional to their weight
+type WeightedPicker interface {
+	Picker
+	AddWeighted(node string, weight float64)
+}
+
+// Placement 表示key放置算法
+// Placement selects a Picker implementation
+type Placement int
+
+const (
+	// Ring 带虚拟节点的一致性哈希环，即Map，是默认的算法
+	Ring Placement = iota
+	// Rendezvous 最高随机权重（HRW）哈希，每个key选择与它组合后哈希值最大的节点，Get的复杂度为O(节点数)
+	Rendezvous
+	// Jump 跳跃一致性哈希（Lamping & Veach），只需要O(1)内存，节点按添加的顺序编号，所有节点必须以相同的顺序添加，删除中间的节点会移动更多的key
+	Jump
+	// Maglev Google Maglev负载均衡器使用的查找表算法，Get为O(1)，增删节点时需要重建查找表
+	Maglev
+)
+
+// String 返回算法的名称
+func (p Placement) String() string {
+	switch p {
+	case Ring:
+		return "ring"
+	case Rendezvous:
+		return "rendezvous"
+	case Jump:
+		return "jump"
+	case Maglev:
+		return "maglev"
+	}
+	return "unknown"
+}
+
+// NewPicker 按placement创建一个Picker，replicas只对Ring有效，fn为nil时使用crc32.ChecksumIEEE
+// NewPicker creates a Picker using the given placement algorithm
+func NewPicker(placement Placement, replicas int, fn Hash) Picker {
+	switch placement {
+	case Rendezvous:
+		return NewRendezvous(fn)
+	case Jump:
+		return NewJump(fn)
+	case Maglev:
+		return NewMaglev(fn)
+	}
+	return New(replicas, fn)
+}
+
+// mix64 是MurmurHash3的fmix64，把32位的哈希值扩散到64位，避免crc32这类哈希函数的线性性影响分布
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+var (
+	_ WeightedPicker = (*Map)(nil)
+	_ WeightedPicker = (*RendezvousMap)(nil)
+	_ Picker         = (*JumpMap)(nil)
+	_ Picker         = (*MaglevMap)(nil)
+)
@@ -0,0 +1,194 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var placements = []Placement{Ring, Rendezvous, Jump, Maglev}
+
+func nodeNames(n int) []string {
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("http://10.0.0.%d:8008", i+1)
+	}
+	return nodes
+}
+
+// 每个节点负责的key的比例与平均值的偏差不超过maxDev
+func TestPickerDistribution(t *testing.T) {
+	const keys = 100000
+	maxDev := map[Placement]float64{Ring: 0.3, Rendezvous: 0.05, Jump: 0.05, Maglev: 0.05}
+	for _, p := range placements {
+		t.Run(p.String(), func(t *testing.T) {
+			picker := NewPicker(p, 50, nil)
+			nodes := nodeNames(8)
+			picker.Add(nodes...)
+			counts := make(map[string]int)
+			for i := 0; i < keys; i++ {
+				counts[picker.Get("key"+strconv.Itoa(i))]++
+			}
+			mean := float64(keys) / float64(len(nodes))
+			var sq float64
+			for _, node := range nodes {
+				dev := (float64(counts[node]) - mean) / mean
+				if math.Abs(dev) > maxDev[p] {
+					t.Errorf("%s owns %d keys, %.1f%% from the mean", node, counts[node], dev*100)
+				}
+				sq += dev * dev
+			}
+			t.Logf("relative stddev %.2f%%", math.Sqrt(sq/float64(len(nodes)))*100)
+		})
+	}
+}
+
+// 增加一个节点时，只有大约1/(n+1)的key移动，并且都移动到新节点上，与新节点的名称排在哪里无关
+func TestPickerStability(t *testing.T) {
+	const keys = 10000
+	for _, p := range placements {
+		for _, added := range []struct{ name, node string }{
+			{"last", "http://10.0.0.9:8008"},
+			{"first", "http://10.0.0.0:8008"},
+		} {
+			t.Run(p.String()+"/"+added.name, func(t *testing.T) {
+				picker := NewPicker(p, 50, nil)
+				nodes := append(nodeNames(8), added.node)
+				picker.Add(nodes[:8]...)
+				before := make([]string, keys)
+				for i := range before {
+					before[i] = picker.Get("key" + strconv.Itoa(i))
+				}
+				picker.Add(nodes[8])
+				moved, misplaced := 0, 0
+				for i, old := range before {
+					if now := picker.Get("key" + strconv.Itoa(i)); now != old {
+						moved++
+						if now != nodes[8] {
+							misplaced++
+						}
+					}
+				}
+				if frac := float64(moved) / keys; frac > 2.0/9 {
+					t.Errorf("%.1f%% of keys moved, expect about %.1f%%", frac*100, 100.0/9)
+				}
+				// Maglev重建查找表时允许少量key在旧节点之间移动
+				if p != Maglev && misplaced > 0 {
+					t.Errorf("%d keys moved between old nodes", misplaced)
+				}
+				if p == Maglev && misplaced > keys/100 {
+					t.Errorf("%d keys moved between old nodes", misplaced)
+				}
+
+				picker.Remove(nodes[8])
+				for i, old := range before {
+					if now := picker.Get("key" + strconv.Itoa(i)); now != old {
+						t.Fatalf("key%d is on %s after removing the new node, expect %s", i, now, old)
+					}
+				}
+			})
+		}
+	}
+}
+
+// 节点以不同的顺序添加时，key的归属相同
+// Jump按添加的顺序给节点编号，这样新节点总是排在末尾，不会移动已有节点的key，所以不在此列，由调用者保证所有节点的顺序相同
+func TestPickerAddOrder(t *testing.T) {
+	nodes := nodeNames(5)
+	reversed := make([]string, len(nodes))
+	for i, node := range nodes {
+		reversed[len(nodes)-1-i] = node
+	}
+	for _, p := range placements {
+		if p == Jump {
+			continue
+		}
+		a, b := NewPicker(p, 50, nil), NewPicker(p, 50, nil)
+		a.Add(nodes...)
+		b.Add(reversed...)
+		for i := 0; i < 1000; i++ {
+			key := "key" + strconv.Itoa(i)
+			if a.Get(key) != b.Get(key) {
+				t.Fatalf("%s: %s is on %s and %s depending on the order of Add", p, key, a.Get(key), b.Get(key))
+			}
+		}
+	}
+}
+
+func TestPickerGetN(t *testing.T) {
+	for _, p := range placements {
+		t.Run(p.String(), func(t *testing.T) {
+			picker := NewPicker(p, 50, nil)
+			picker.Add(nodeNames(5)...)
+			for i := 0; i < 100; i++ {
+				key := "key" + strconv.Itoa(i)
+				nodes := picker.GetN(key, 10)
+				if len(nodes) != 5 || nodes[0] != picker.Get(key) {
+					t.Fatalf("GetN(%s) = %v, Get = %s", key, nodes, picker.Get(key))
+				}
+				seen := make(map[string]bool)
+				for _, node := range nodes {
+					if seen[node] {
+						t.Fatalf("GetN(%s) = %v has duplicates", key, nodes)
+					}
+					seen[node] = true
+				}
+			}
+			if len(picker.Nodes()) != 5 {
+				t.Fatalf("Nodes() = %v", picker.Nodes())
+			}
+		})
+	}
+}
+
+// 取较少的节点时得到的是取全部节点时的前缀，调用者可以先少取，不够时再多取
+func TestPickerGetNPrefix(t *testing.T) {
+	nodes := nodeNames(20)
+	for _, p := range placements {
+		picker := NewPicker(p, 50, nil)
+		picker.Add(nodes...)
+		for i := 0; i < 100; i++ {
+			key := "key" + strconv.Itoa(i)
+			all := picker.GetN(key, len(nodes))
+			for _, n := range []int{1, 3, 9, 16} {
+				if got := picker.GetN(key, n); !reflect.DeepEqual(got, all[:n]) {
+					t.Fatalf("%s: GetN(%s, %d) = %v, expect %v", p, key, n, got, all[:n])
+				}
+			}
+		}
+	}
+}
+
+func TestRendezvousWeighted(t *testing.T) {
+	picker := NewRendezvous(nil)
+	picker.AddWeighted("a", 1)
+	picker.AddWeighted("b", 3)
+	counts := make(map[string]int)
+	for i := 0; i < 40000; i++ {
+		counts[picker.Get("key"+strconv.Itoa(i))]++
+	}
+	if ratio := float64(counts["b"]) / float64(counts["a"]); ratio < 2.7 || ratio > 3.3 {
+		t.Fatalf("b owns %.2f times as many keys as a, expect 3", ratio)
+	}
+}
+
+func BenchmarkPickerGet(b *testing.B) {
+	for _, p := range placements {
+		for _, n := range []int{8, 64} {
+			b.Run(fmt.Sprintf("%s/%d", p, n), func(b *testing.B) {
+				picker := NewPicker(p, 50, nil)
+				picker.Add(nodeNames(n)...)
+				keys := make([]string, 1024)
+				for i := range keys {
+					keys[i] = "key" + strconv.Itoa(i)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					picker.Get(keys[i%len(keys)])
+				}
+			})
+		}
+	}
+}
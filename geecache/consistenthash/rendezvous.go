@@ -0,0 +1,135 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"math"
+	"sort"
+)
+
+// partialSelectMax GetN最多取这么多个节点时使用部分选择，否则对所有节点排序
+const partialSelectMax = 8
+
+// RendezvousMap 实现了最高随机权重（Highest Random Weight）哈希
+// 对每个key，把key的哈希值与每个节点的哈希值组合后计算一个分数，分数最高的节点负责这个key
+// 增删节点时，只有分数最高的节点发生变化的key会移动，并且按分数排序就能直接得到后继节点
+// 带权重的节点使用对数方法计算分数 weight / -ln(u)，u是(0,1)之间的均匀随机数，这样节点负责的key的比例与权重成正比
+// RendezvousMap places keys using rendezvous (HRW) hashing
+type RendezvousMap struct {
+	hash    Hash
+	nodes   []string // Sorted
+	hashes  map[string]uint64
+	weights map[string]float64
+}
+
+// NewRendezvous creates a RendezvousMap instance
+func NewRendezvous(fn Hash) *RendezvousMap {
+	m := &RendezvousMap{
+		hash:    fn,
+		hashes:  make(map[string]uint64),
+		weights: make(map[string]float64),
+	}
+	if m.hash == nil {
+		m.hash = crc32.ChecksumIEEE
+	}
+	return m
+}
+
+// Add adds some nodes with weight 1
+func (m *RendezvousMap) Add(nodes ...string) {
+	for _, node := range nodes {
+		if _, ok := m.hashes[node]; !ok {
+			m.AddWeighted(node, 1)
+		}
+	}
+}
+
+// AddWeighted 添加一个带权重的节点，节点已经存在时更新它的权重
+// AddWeighted adds a node whose share of keys is proportional to weight
+func (m *RendezvousMap) AddWeighted(node string, weight float64) {
+	if weight <= 0 {
+		weight = 1
+	}
+	if _, ok := m.hashes[node]; !ok {
+		m.hashes[node] = mix64(uint64(m.hash([]byte(node))))
+		m.nodes = append(m.nodes, node)
+		sort.Strings(m.nodes)
+	}
+	m.weights[node] = weight
+}
+
+// Remove removes some nodes
+func (m *RendezvousMap) Remove(nodes ...string) {
+	for _, node := range nodes {
+		delete(m.hashes, node)
+		delete(m.weights, node)
+	}
+	kept := m.nodes[:0]
+	for _, node := range m.nodes {
+		if _, ok := m.hashes[node]; ok {
+			kept = append(kept, node)
+		}
+	}
+	m.nodes = kept
+}
+
+// Nodes returns the nodes sorted by name
+func (m *RendezvousMap) Nodes() []string {
+	return append([]string(nil), m.nodes...)
+}
+
+// score 计算node对于哈希值为keyHash的key的分数
+func (m *RendezvousMap) score(node string, keyHash uint64) float64 {
+	h := mix64(m.hashes[node] ^ keyHash)
+	u := (float64(h>>11) + 0.5) / (1 << 53) // 取高53位，映射到(0,1)
+	return m.weights[node] / -math.Log(u)
+}
+
+// Get returns the node with the highest score for key
+func (m *RendezvousMap) Get(key string) string {
+	keyHash := mix64(uint64(m.hash([]byte(key))))
+	best, bestScore := "", -1.0
+	for _, node := range m.nodes { // nodes有序，分数相同时选择名称较小的节点
+		if s := m.score(node, keyHash); s > bestScore {
+			best, bestScore = node, s
+		}
+	}
+	return best
+}
+
+// GetN 返回分数最高的n个节点，n较小时（例如PickPeer只需要所属节点和少数后继节点）只做部分选择，不对所有节点排序
+// GetN returns up to n nodes in descending score order
+func (m *RendezvousMap) GetN(key string, n int) []string {
+	if len(m.nodes) == 0 || n <= 0 {
+		return nil
+	}
+	keyHash := mix64(uint64(m.hash([]byte(key))))
+	type scored struct {
+		node  string
+		score float64
+	}
+	ranked := make([]scored, len(m.nodes))
+	for i, node := range m.nodes {
+		ranked[i] = scored{node, m.score(node, keyHash)}
+	}
+	n = min(n, len(ranked))
+	if n <= partialSelectMax {
+		for i := 0; i < n; i++ {
+			best := i
+			for j := i + 1; j < len(ranked); j++ {
+				if ranked[j].score > ranked[best].score {
+					best = j
+				}
+			}
+			ranked[i], ranked[best] = ranked[best], ranked[i]
+		}
+	} else {
+		sort.Slice(ranked, func(i, j int) bool {
+			return ranked[i].score > ranked[j].score
+		})
+	}
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = ranked[i].node
+	}
+	return nodes
+}
@@ -1,10 +1,27 @@
 package geecache
 
 import (
+	pb "LinJz_gee_cache/geecache/geecachepb"
+	"LinJz_gee_cache/geecache/lru"
 	"LinJz_gee_cache/geecache/singleflight"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"slices"
+	"sort"
 	"sync"
+	"time"
+)
+
+const (
+	// hotCacheRatio hotCache占用cacheBytes的1/hotCacheRatio，剩余的留给mainCache
+	hotCacheRatio = 8
+	// hotCacheOdds 从远程节点获取的值有1/hotCacheOdds的概率被放入hotCache
+	hotCacheOdds = 10
+	// defaultPeerAttempts 默认只尝试key的所属节点，失败后直接从本地加载
+	defaultPeerAttempts = 1
 )
 
 // Getter 定义接口Getter和回调函数Get(key string)([]byte,error),参数为key，返回值为[]byte。
@@ -23,17 +40,84 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// ContextGetter 是可选的接口，回调函数可以通过ctx感知调用者的取消和超时，例如把ctx传给数据库查询
+// A ContextGetter loads data for a key and honours the caller's context
+type ContextGetter interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// ContextGetterFunc 是实现了ContextGetter接口的接口型函数，同时也实现了Getter接口，所以可以直接传给NewGroup
+// A ContextGetterFunc implements ContextGetter and Getter with a function
+type ContextGetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+// GetContext implements ContextGetter interface function
+func (f ContextGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// Get implements Getter interface function with a background context
+func (f ContextGetterFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+// TTLGetter 是可选的接口，回调函数在返回源数据的同时返回该数据的有效期，Group会据此设置缓存的过期时间，ttl<=0表示永不过期
+// A TTLGetter loads data for a key together with how long it stays fresh
+type TTLGetter interface {
+	GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error)
+}
+
+// TTLGetterFunc 与GetterFunc类似，是实现了TTLGetter接口的接口型函数，同时也实现了Getter和ContextGetter接口，所以可以直接传给NewGroup
+// A TTLGetterFunc implements TTLGetter, ContextGetter and Getter with a function
+type TTLGetterFunc func(ctx context.Context, key string) ([]byte, time.Duration, error)
+
+// GetWithTTL implements TTLGetter interface function
+func (f TTLGetterFunc) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return f(ctx, key)
+}
+
+// GetContext implements ContextGetter interface function, the ttl is dropped
+func (f TTLGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	b, _, err := f(ctx, key)
+	return b, err
+}
+
+// Get implements Getter interface function, the ttl is dropped
+func (f TTLGetterFunc) Get(key string) ([]byte, error) {
+	return f.GetContext(context.Background(), key)
+}
+
 // Group 一个Group可以认为是一个缓存的命名空间，每个Group拥有一个唯一的名称name，比如可以创建三个Group，缓存学生的成绩命名为scores，换成学生信息的命名为info，缓存学生课程的命名为courses
 // 第二个属性是getter Getter，即缓存未命中时获取源数据的回调（callback）
-// 第三个属性是mainCache cache，即一开始实现的并发缓存
+// 第三个属性是mainCache cache，即一开始实现的并发缓存，存放本节点负责的key
+// 第四个属性是hotCache cache，存放属于其他节点、但在本节点被频繁访问的key，避免每次都向远程节点发请求
 // A Group is a cache namespace and associated data loaded spread over
 type Group struct {
 	name      string
 	getter    Getter
 	mainCache cache
+	hotCache  cache
 	peers     PeerPicker
 	// use singleflight.Group to make sure each key is only fetched once
 	loader *singleflight.Group
+	stats  groupStats
+	opts   GroupOptions
+}
+
+// GroupOptions 是Group的可选配置，零值表示使用默认值
+// GroupOptions are the configurations of a Group
+type GroupOptions struct {
+	// PeerAttempts 从远程节点加载时最多尝试的节点数，所属节点失败后依次尝试它在哈希环上的后继节点，都失败或轮到本节点时才从本地加载
+	// 需要PeerPicker实现PeerListPicker接口，默认为1，即只尝试所属节点
+	PeerAttempts int
+	// Eviction mainCache和hotCache使用的淘汰策略，默认为lru.LRU，扫描式访问较多时可以使用lru.ARC或lru.LFU
+	// 从远程节点获取的冷数据容易挤掉热点条目时，可以使用lru.TinyLFU，只有访问频率足够高的key才会进入缓存
+	// 读多写少、锁竞争明显时可以使用lru.Clock，查找只需要读锁
+	Eviction lru.PolicyKind
+	// CacheShards mainCache和hotCache各自分成的shard数，每个shard有独立的锁，容量为总容量的1/CacheShards
+	// 每个key只能使用所在shard的容量，比shard容量还大的值放入后马上就会被淘汰，所以shard数会被限制为每个shard至少有64KB的容量
+	// hotCache只占cacheBytes的1/8，它的shard数会先被限制
+	// 并发较高时可以设置为CPU核数左右来减少锁竞争，默认为1，即不分片
+	CacheShards int
 }
 
 var (
@@ -42,8 +126,15 @@ var (
 )
 
 // NewGroup 构建函数NewGroup用来实例化Group，并且将group存储在全局变量groups中
+// cacheBytes的1/hotCacheRatio分给hotCache，其余分给mainCache
 // NewGroup create a new instance of Group
 func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+	return NewGroupOpts(name, cacheBytes, getter, nil)
+}
+
+// NewGroupOpts 与NewGroup相同，但可以通过o修改默认配置，o可以为nil
+// NewGroupOpts create a new instance of Group with the given options
+func NewGroupOpts(name string, cacheBytes int64, getter Getter, o *GroupOptions) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
@@ -53,11 +144,17 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 	g := &Group{
 		name:   name,
 		getter: getter,
-		mainCache: cache{
-			cacheBytes: cacheBytes,
-		},
 		loader: &singleflight.Group{},
 	}
+	if o != nil {
+		g.opts = *o
+	}
+	hotBytes := cacheBytes / hotCacheRatio
+	g.mainCache = newCache(cacheBytes-hotBytes, g.opts.Eviction, g.opts.CacheShards)
+	g.hotCache = newCache(hotBytes, g.opts.Eviction, g.opts.CacheShards)
+	if g.opts.PeerAttempts <= 0 {
+		g.opts.PeerAttempts = defaultPeerAttempts
+	}
 	groups[name] = g
 	return g
 }
@@ -81,38 +178,170 @@ func GetGroup(name string) *Group {
 	return g
 }
 
+// allGroups 按名称顺序返回所有的Group
+func allGroups() []*Group {
+	mu.RLock()
+	defer mu.RUnlock()
+	gs := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		gs = append(gs, g)
+	}
+	sort.Slice(gs, func(i, j int) bool { return gs[i].name < gs[j].name })
+	return gs
+}
+
 // Get 接下来是 GeeCache 最为核心的方法Get
 // Get方法实现了上述所说的流程（1）和（3），即检查是否有缓存，有则返回（1），无则调用`回调函数`，获取值并添加到缓存然后再返回缓存值（3），（2）是从远程节点获取，由于第二天是实现单机，暂时还没有远程节点
 // 流程（1）：从mainCache中查找缓存，如果存在则返回缓存值
 // 流程（3）：缓存不存在，则调用load方法，load调用getLocally（分布式场景下会调用getFromPeer从其他节点获取），getLocally调用用户回调函数g.getter.Get()获取源数据，并且将源数据添加到缓存mainCache中（通过populateCache方法）
 // Get value for a key from cache
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与Get相同，ctx会被传递给远程节点的HTTP请求、singleflight的等待以及用户的回调函数，用来取消请求或者限制超时时间
+// 同一个key的加载由多个调用者共享，所以回调函数收到的ctx保留了ctx中的值，但没有截止时间（ctx.Deadline()返回false），
+// 调用者超时后放弃等待，所有调用者都放弃时回调函数的ctx才会被取消，见singleflight.Group.DoContext
+// GetContext gets value for a key, bounded by ctx
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
+	g.stats.gets.Add(1)
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
-	if v, ok := g.mainCache.get(key); ok {
+	if v, ok := g.lookupCache(key); ok {
+		g.stats.cacheHits.Add(1)
 		log.Println("[GeeCache] hit")
 		return v, nil
 	}
 	// 没有击中缓存
-	return g.load(key)
+	return g.load(ctx, key)
+}
+
+// peerRequestKey 标记ctx来自远程节点的请求
+type peerRequestKey struct{}
+
+// servePeer 处理来自远程节点的查询请求，返回值中带有过期时间等元数据，HTTPPool和GRPCPool共用
+// 远程节点选中本节点，说明本节点是key的所属节点或者所属节点不可用时的后继节点，所以缓存未命中时直接从本地加载，不再转发给其他节点，避免请求在节点之间来回转发
+func (g *Group) servePeer(ctx context.Context, key string) (*pb.GetResponse, error) {
+	view, err := g.GetContext(context.WithValue(ctx, peerRequestKey{}, true), key)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.GetResponse{Value: view.ByteSlice(), MinuteQps: g.stats.minuteQPS.rate()}
+	if !view.Expire().IsZero() {
+		res.Expire = view.Expire().UnixNano()
+	}
+	return res, nil
+}
+
+// Remove 主动删除key对应的缓存，用于数据源更新后让缓存失效
+// 首先删除本地mainCache中的缓存，然后如果key属于远程节点，则向该节点发送删除请求
+// 所属节点按哈希环确定，即使它正在熔断或者负载达到上限也要通知它，否则它恢复后会继续返回旧值
+// 如果PeerPicker实现了PeerLister接口，还会通知其余所有节点，删除它们hotCache中的副本，返回所有失败的删除请求的错误
+// Remove deletes the key from the cache, including the copy held by the owning peer and the hot copies held by other peers
+func (g *Group) Remove(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	g.removeLocally(key)
+	owners := g.ownerPeers(key, 1)
+	peers := owners
+	if lister, ok := g.peers.(PeerLister); ok {
+		for _, peer := range lister.Peers() {
+			if !slices.Contains(owners, peer) {
+				peers = append(peers, peer)
+			}
+		}
+	}
+	var errs []error
+	for _, peer := range peers {
+		if err := peer.Remove(ctx, g.name, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ownerPeers 返回key的所属节点及其后继节点中最多n个远程节点，PeerPicker没有实现OwnerPicker时只返回PickPeer的结果
+func (g *Group) ownerPeers(key string, n int) []PeerGetter {
+	if g.peers == nil {
+		return nil
+	}
+	if picker, ok := g.peers.(OwnerPicker); ok {
+		return picker.PickOwners(key, n)
+	}
+	if peer, ok := g.peers.PickPeer(key); ok {
+		return []PeerGetter{peer}
+	}
+	return nil
+}
+
+// 只删除本地mainCache和hotCache中的缓存，远程节点收到删除请求时调用，避免请求在节点之间来回转发
+func (g *Group) removeLocally(key string) {
+	g.mainCache.remove(key)
+	g.hotCache.remove(key)
+}
+
+// Keys 返回本节点缓存的所有没有过期的key，先是mainCache中的，然后是hotCache中的，不包括远程节点上的缓存
+// Keys returns the keys cached on this node
+func (g *Group) Keys() []string {
+	return append(g.mainCache.keys(), g.hotCache.keys()...)
+}
+
+// Purge 清空本节点的mainCache和hotCache，远程节点上的缓存不受影响，统计数据保留
+// Purge drops every entry cached on this node
+func (g *Group) Purge() {
+	g.mainCache.purge()
+	g.hotCache.purge()
+}
+
+// lookupCache 先查找mainCache，再查找hotCache
+func (g *Group) lookupCache(key string) (value ByteView, ok bool) {
+	if value, ok = g.mainCache.get(key); ok {
+		return
+	}
+	if value, ok = g.hotCache.get(key); ok {
+		g.stats.hotCacheHits.Add(1)
+	}
+	return
 }
 
 // 修改load方法，使用PickPeer()方法，使用PickPeer()方法选择节点，若非本地节点，则调用getFromPeer()从远程获取，若是本地节点或失败，则回退到getLocally()。
 // 修改geecache.go中的Group，添加成员变量loader，并更新构建函数NewGroup
 // 修改load函数，将原来的load的逻辑，使用g.loader.Do包裹起来，这样确保了并发场景下针对相同的key，load过程只会调用一次
-func (g *Group) load(key string) (value ByteView, err error) {
+// 使用DoContext代替Do，这样等待其他调用者加载结果的请求也能被ctx取消
+// 加载本身使用DoContext传入的ctx，第一个调用者取消时不会影响其他调用者，所有调用者都放弃时才取消加载，已经取消的加载不再访问其他节点和数据源
+// 所属节点失败时，依次尝试pickPeers返回的后继节点，而不是马上从本地加载，避免某个节点宕机时所有节点都去访问数据源
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
+	g.stats.loads.Add(1)
 	// each key is only fetched once(either locally or remotely),regardless of the number of concurrent callers(无论并发呼叫者的数量如何)
-	viewi, err := g.loader.Do(key, func() (any, error) {
-		if g.peers != nil {
-			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err = g.getFromPeer(peer, key); err == nil { // 注意看，这里使用的是=，而不是:=，再看看方法返回值，定义了返回值名，说明会自动返回值
+	// fn在单独的协程中执行，调用者放弃等待后可能仍在运行，所以不能写入value和err
+	viewi, err := g.loader.DoContext(ctx, key, func(ctx context.Context) (any, error) {
+		g.stats.loadsDeduped.Add(1) // 只有真正执行加载的调用者才会走到这里
+		if ctx.Value(peerRequestKey{}) == nil {
+			for _, peer := range g.pickPeers(key) {
+				value, err := g.getFromPeer(ctx, peer, key)
+				if err == nil {
+					g.stats.peerLoads.Add(1)
 					return value, nil
 				}
+				if ctx.Err() != nil { // 所有调用者都已经放弃，不再尝试其他节点
+					return nil, ctx.Err()
+				}
+				g.stats.peerErrors.Add(1)
 				log.Println("[GeeCache] Failed to get from peer", err)
 			}
 		}
-		return g.getLocally(key)
+		if ctx.Err() != nil { // 普通的Getter不理会ctx，没有人等待结果时不要再访问数据源
+			return nil, ctx.Err()
+		}
+		value, err := g.getLocally(ctx, key)
+		if err != nil {
+			g.stats.localLoadErrs.Add(1)
+			return nil, err
+		}
+		g.stats.localLoads.Add(1)
+		return value, nil
 	})
 	if err == nil {
 		return viewi.(ByteView), nil
@@ -120,28 +349,99 @@ func (g *Group) load(key string) (value ByteView, err error) {
 	return
 }
 
+// pickPeers 返回加载key时需要依次尝试的远程节点，为空表示应该从本地加载
+func (g *Group) pickPeers(key string) []PeerGetter {
+	if g.peers == nil {
+		return nil
+	}
+	if picker, ok := g.peers.(PeerListPicker); ok && g.opts.PeerAttempts > 1 {
+		return picker.PickPeers(key, g.opts.PeerAttempts)
+	}
+	if peer, ok := g.peers.PickPeer(key); ok {
+		return []PeerGetter{peer}
+	}
+	return nil
+}
+
 // 新增getFromPeer方法，使用实现了PeerGetter接口的httpGetter从访问远程节点获取缓存值
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
-	bytes, err := peer.Get(g.name, key)
+// 如果peer实现了ProtoPeerGetter接口，则使用protobuf协议，并保留远程节点返回的过期时间；如果peer实现了ContextPeerGetter接口，则把ctx传给它
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
+	var (
+		bytes  []byte
+		expire time.Time
+		err    error
+	)
+	switch p := peer.(type) {
+	case ProtoPeerGetter:
+		res := &pb.GetResponse{}
+		if err = p.GetProto(ctx, &pb.GetRequest{Group: g.name, Key: []byte(key)}, res); err == nil {
+			bytes = res.GetValue()
+			if res.GetExpire() != 0 {
+				expire = time.Unix(0, res.GetExpire())
+			}
+		}
+	case ContextPeerGetter:
+		bytes, err = p.GetContext(ctx, g.name, key)
+	default:
+		bytes, err = peer.Get(g.name, key)
+	}
 	if err != nil {
 		return ByteView{}, err
 	}
-	return ByteView{b: bytes}, nil
+	value := ByteView{b: bytes, e: expire}
+	// 只把一小部分远程节点的值放入hotCache，频繁访问的key更有机会被选中
+	if rand.Intn(hotCacheOdds) == 0 {
+		g.populateCache(key, value, &g.hotCache)
+	}
+	return value, nil
 }
 
 // 调用用户回调函数g.getter.Get()获取源数据，并且将源数据添加到缓存mainCache中（通过populateCache方法）
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bytes, err := g.getter.Get(key)
+// 所属节点不可用时，后继节点或者本节点会代替它从本地加载，这时本节点不是key的所属节点，Remove不会通知到它，所以值只放入hotCache
+// 如果回调函数实现了TTLGetter接口，则根据返回的ttl设置缓存的过期时间；如果实现了ContextGetter接口，则把ctx传给它
+func (g *Group) getLocally(ctx context.Context, key string) (ByteView, error) {
+	var (
+		bytes []byte
+		ttl   time.Duration
+		err   error
+	)
+	switch getter := g.getter.(type) {
+	case TTLGetter:
+		bytes, ttl, err = getter.GetWithTTL(ctx, key)
+	case ContextGetter:
+		bytes, err = getter.GetContext(ctx, key)
+	default:
+		bytes, err = g.getter.Get(key)
+	}
 	if err != nil {
 		return ByteView{}, err
 	}
 
 	value := ByteView{b: cloneBytes(bytes)} // b是只读的，使用cloneBytes()方法返回一个拷贝，当防止缓存值被外部程序修改
-	g.populateCache(key, value)
+	if ttl > 0 {
+		value.e = time.Now().Add(ttl)
+	}
+	if g.owns(key) {
+		g.populateCache(key, value, &g.mainCache)
+	} else {
+		g.populateCache(key, value, &g.hotCache)
+	}
 	return value, nil
 }
 
-// 将源数据添加到缓存mainCache中
-func (g *Group) populateCache(key string, value ByteView) {
-	g.mainCache.add(key, value)
+// owns 返回本节点是否是key在哈希环上的所属节点，不考虑所属节点是否熔断或者负载达到上限
+func (g *Group) owns(key string) bool {
+	if g.peers == nil {
+		return true
+	}
+	if picker, ok := g.peers.(OwnerPicker); ok {
+		return len(picker.PickOwners(key, 1)) == 0
+	}
+	_, ok := g.peers.PickPeer(key)
+	return !ok
+}
+
+// 将数据添加到指定的缓存中，本节点负责的key放入mainCache，远程节点的key放入hotCache
+func (g *Group) populateCache(key string, value ByteView, c *cache) {
+	c.add(key, value)
 }
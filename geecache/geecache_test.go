@@ -1,10 +1,15 @@
 package geecache
 
 import (
+	"LinJz_gee_cache/geecache/lru"
+	"context"
 	"fmt"
 	"log"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 )
 
 var db = map[string]string{
@@ -58,3 +63,290 @@ func TestGet(t *testing.T) {
 		t.Fatalf("the value of unknow should be empty, but %s got", view)
 	}
 }
+
+// 测试回调函数返回的ttl是否生效，过期之后再次Get会重新调用回调函数
+func TestGetWithTTL(t *testing.T) {
+	loads := 0
+	gee := NewGroup("ttl", 2<<10, TTLGetterFunc(
+		func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+			loads++
+			return []byte(key), 20 * time.Millisecond, nil
+		}))
+
+	for i := 0; i < 2; i++ {
+		if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" {
+			t.Fatal("failed to get value")
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("Tom loaded %d times before expire, expect 1", loads)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := gee.Get("Tom"); err != nil || loads != 2 {
+		t.Fatalf("Tom loaded %d times after expire, expect 2", loads)
+	}
+}
+
+// fakePeer 假装所有key都属于它，记录收到的请求，用来测试与远程节点的交互
+type fakePeer struct {
+	gets    int
+	removed []string
+}
+
+func (p *fakePeer) PickPeer(key string) (PeerGetter, bool) {
+	return p, true
+}
+
+func (p *fakePeer) Get(group string, key string) ([]byte, error) {
+	p.gets++
+	return []byte(key), nil
+}
+
+func (p *fakePeer) Remove(ctx context.Context, group string, key string) error {
+	p.removed = append(p.removed, group+"/"+key)
+	return nil
+}
+
+func TestRemove(t *testing.T) {
+	loads := 0
+	gee := NewGroup("remove", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}))
+
+	gee.Get("Tom")
+	if err := gee.Remove(context.Background(), "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	gee.Get("Tom")
+	if loads != 2 {
+		t.Fatalf("Tom loaded %d times, expect 2 after remove", loads)
+	}
+
+	peer := &fakePeer{}
+	gee.RegisterPeers(peer)
+	if err := gee.Remove(context.Background(), "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(peer.removed, []string{"remove/Tom"}) {
+		t.Fatalf("remove was not sent to the owner, got %v", peer.removed)
+	}
+}
+
+// 测试ctx超时后GetContext能够提前返回，并且ctx被传递给了回调函数
+func TestGetContext(t *testing.T) {
+	gee := NewGroup("context", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return []byte(key), nil
+			}
+		}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "Tom"); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext error = %v, expect %v", err, context.DeadlineExceeded)
+	}
+}
+
+// 测试同一个key的第一个调用者超时后，其他调用者仍然能拿到加载结果
+func TestGetContextLeaderCancel(t *testing.T) {
+	var once sync.Once
+	started, release := make(chan struct{}), make(chan struct{})
+	gee := NewGroup("leader", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			once.Do(func() { close(started) })
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-release:
+				return []byte(key), nil
+			}
+		}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := gee.GetContext(ctx, "Tom")
+		leader <- err
+	}()
+	<-started
+	follower := make(chan string)
+	go func() {
+		view, _ := gee.GetContext(context.Background(), "Tom")
+		follower <- view.String()
+	}()
+	for gee.stats.loads.Load() < 2 { // 等待follower进入load
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-leader; err != context.Canceled {
+		t.Fatalf("leader error = %v, expect %v", err, context.Canceled)
+	}
+	close(release)
+	if v := <-follower; v != "Tom" {
+		t.Fatalf("follower got %q, expect Tom", v)
+	}
+}
+
+// 测试远程节点的值会被放入hotCache，之后直接从hotCache命中
+func TestHotCache(t *testing.T) {
+	gee := NewGroup("hot", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, fmt.Errorf("%s should be loaded from peer", key)
+		}))
+	peer := &fakePeer{}
+	gee.RegisterPeers(peer)
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" {
+			t.Fatal("failed to get value from peer")
+		}
+	}
+	if hits := gee.Stats().HotCacheHits; hits == 0 || int(hits)+peer.gets != n {
+		t.Fatalf("hot cache hits = %d, peer gets = %d", hits, peer.gets)
+	}
+}
+
+func TestStats(t *testing.T) {
+	gee := NewGroup("stats", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+
+	gee.Get("Tom")
+	gee.Get("Tom")
+	gee.Get("unknown")
+
+	expect := Stats{Gets: 3, CacheHits: 1, Loads: 2, LoadsDeduped: 2, LocalLoads: 1, LocalLoadErrs: 1}
+	if s := gee.Stats(); s != expect {
+		t.Fatalf("Stats() = %+v, expect %+v", s, expect)
+	}
+	if s := gee.CacheStats(MainCache); s.Items != 1 || s.Hits != 1 || s.Bytes != int64(len("Tom")+len(db["Tom"])) {
+		t.Fatalf("CacheStats(MainCache) = %+v", s)
+	}
+}
+
+// listPeer 依次返回peers，模拟所属节点及其后继节点
+type listPeer struct {
+	peers []PeerGetter
+}
+
+func (p *listPeer) PickPeer(key string) (PeerGetter, bool) {
+	return p.peers[0], true
+}
+
+func (p *listPeer) PickPeers(key string, n int) []PeerGetter {
+	if n > len(p.peers) {
+		n = len(p.peers)
+	}
+	return p.peers[:n]
+}
+
+// downPeer 模拟一个不可用的节点
+type downPeer struct {
+	fakePeer
+}
+
+func (p *downPeer) Get(group string, key string) ([]byte, error) {
+	p.gets++
+	return nil, fmt.Errorf("peer is down")
+}
+
+// 测试所属节点失败时，会先尝试后继节点，而不是直接从本地加载
+func TestPeerFailover(t *testing.T) {
+	loads := 0
+	gee := NewGroupOpts("failover", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}), &GroupOptions{PeerAttempts: 2})
+	down, successor := &downPeer{}, &fakePeer{}
+	gee.RegisterPeers(&listPeer{peers: []PeerGetter{down, &downPeer{}, successor}})
+
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" {
+		t.Fatalf("failed to get value: %v", err)
+	}
+	if down.gets != 1 || loads != 1 || successor.gets != 0 {
+		t.Fatalf("owner gets = %d, local loads = %d, successor gets = %d", down.gets, loads, successor.gets)
+	}
+
+	gee = NewGroupOpts("failover3", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}), &GroupOptions{PeerAttempts: 3})
+	gee.RegisterPeers(&listPeer{peers: []PeerGetter{down, &downPeer{}, successor}})
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" {
+		t.Fatalf("failed to get value: %v", err)
+	}
+	if successor.gets != 1 || loads != 1 {
+		t.Fatalf("local loads = %d, successor gets = %d", loads, successor.gets)
+	}
+	if s := gee.Stats(); s.PeerErrors != 2 || s.PeerLoads != 1 {
+		t.Fatalf("Stats() = %+v", s)
+	}
+}
+
+func TestGroupEviction(t *testing.T) {
+	loads := 0
+	gee := NewGroupOpts("eviction", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}), &GroupOptions{Eviction: lru.LFU})
+
+	for i := 0; i < 2; i++ {
+		if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" {
+			t.Fatal("failed to get Tom")
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("Tom loaded %d times, expect 1", loads)
+	}
+	if _, ok := gee.mainCache.shards[0].lru.(*lru.LFUCache[string, ByteView]); !ok {
+		t.Fatalf("mainCache uses %T, expect LFU", gee.mainCache.shards[0].lru)
+	}
+}
+
+func TestKeysAndPurge(t *testing.T) {
+	loads := 0
+	gee := NewGroupOpts("purge", 8*minShardBytes, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}), &GroupOptions{CacheShards: 4})
+	if n := len(gee.mainCache.shards); n != 4 {
+		t.Fatalf("mainCache has %d shards, expect 4", n)
+	}
+
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		gee.Get(key)
+	}
+	keys := gee.Keys()
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"Jack", "Sam", "Tom"}) {
+		t.Fatalf("Keys() = %v", keys)
+	}
+
+	gee.Purge()
+	if keys := gee.Keys(); len(keys) != 0 {
+		t.Fatalf("Keys() = %v after Purge", keys)
+	}
+	gee.Get("Tom")
+	if loads != 4 {
+		t.Fatalf("%d loads, expect Tom to be loaded again after Purge", loads)
+	}
+	if s := gee.CacheStats(MainCache); s.Items != 1 || s.Evictions != 0 {
+		t.Fatalf("CacheStats(MainCache) = %+v", s)
+	}
+}
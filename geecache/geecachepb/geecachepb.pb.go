@@ -0,0 +1,320 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: geecachepb.proto
+
+package geecachepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// GetRequest 是节点之间查询缓存的请求
+type GetRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Group string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	// key使用bytes而不是string，因为Go的string可以包含任意字节，而proto3的string要求是合法的UTF-8
+	Key           []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_geecachepb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *GetRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+// GetResponse 是节点之间查询缓存的响应
+type GetResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// expire是缓存值的过期时间（Unix纳秒），0表示永不过期
+	Expire int64 `protobuf:"varint,2,opt,name=expire,proto3" json:"expire,omitempty"`
+	// minute_qps是该Group最近一分钟内收到的远程请求的QPS
+	MinuteQps float64 `protobuf:"fixed64,3,opt,name=minute_qps,json=minuteQps,proto3" json:"minute_qps,omitempty"`
+	// error只在BatchGet中使用，表示这一个key加载失败的原因，不影响同一批次中的其他key
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_geecachepb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *GetResponse) GetExpire() int64 {
+	if x != nil {
+		return x.Expire
+	}
+	return 0
+}
+
+func (x *GetResponse) GetMinuteQps() float64 {
+	if x != nil {
+		return x.MinuteQps
+	}
+	return 0
+}
+
+func (x *GetResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// RemoveRequest 是节点之间删除缓存的请求
+type RemoveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Key           []byte                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveRequest) Reset() {
+	*x = RemoveRequest{}
+	mi := &file_geecachepb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveRequest) ProtoMessage() {}
+
+func (x *RemoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveRequest.ProtoReflect.Descriptor instead.
+func (*RemoveRequest) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RemoveRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *RemoveRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+type RemoveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveResponse) Reset() {
+	*x = RemoveResponse{}
+	mi := &file_geecachepb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveResponse) ProtoMessage() {}
+
+func (x *RemoveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveResponse.ProtoReflect.Descriptor instead.
+func (*RemoveResponse) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{3}
+}
+
+var File_geecachepb_proto protoreflect.FileDescriptor
+
+const file_geecachepb_proto_rawDesc = "" +
+	"\n" +
+	"\x10geecachepb.proto\x12\n" +
+	"geecachepb\"4\n" +
+	"\n" +
+	"GetRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\fR\x03key\"p\n" +
+	"\vGetResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x16\n" +
+	"\x06expire\x18\x02 \x01(\x03R\x06expire\x12\x1d\n" +
+	"\n" +
+	"minute_qps\x18\x03 \x01(\x01R\tminuteQps\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"7\n" +
+	"\rRemoveRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\fR\x03key\"\x10\n" +
+	"\x0eRemoveResponse2\xc6\x01\n" +
+	"\n" +
+	"GroupCache\x126\n" +
+	"\x03Get\x12\x16.geecachepb.GetRequest\x1a\x17.geecachepb.GetResponse\x12?\n" +
+	"\bBatchGet\x12\x16.geecachepb.GetRequest\x1a\x17.geecachepb.GetResponse(\x010\x01\x12?\n" +
+	"\x06Remove\x12\x19.geecachepb.RemoveRequest\x1a\x1a.geecachepb.RemoveResponseB%Z#LinJz_gee_cache/geecache/geecachepbb\x06proto3"
+
+var (
+	file_geecachepb_proto_rawDescOnce sync.Once
+	file_geecachepb_proto_rawDescData []byte
+)
+
+func file_geecachepb_proto_rawDescGZIP() []byte {
+	file_geecachepb_proto_rawDescOnce.Do(func() {
+		file_geecachepb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_geecachepb_proto_rawDesc), len(file_geecachepb_proto_rawDesc)))
+	})
+	return file_geecachepb_proto_rawDescData
+}
+
+var file_geecachepb_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_geecachepb_proto_goTypes = []any{
+	(*GetRequest)(nil),     // 0: geecachepb.GetRequest
+	(*GetResponse)(nil),    // 1: geecachepb.GetResponse
+	(*RemoveRequest)(nil),  // 2: geecachepb.RemoveRequest
+	(*RemoveResponse)(nil), // 3: geecachepb.RemoveResponse
+}
+var file_geecachepb_proto_depIdxs = []int32{
+	0, // 0: geecachepb.GroupCache.Get:input_type -> geecachepb.GetRequest
+	0, // 1: geecachepb.GroupCache.BatchGet:input_type -> geecachepb.GetRequest
+	2, // 2: geecachepb.GroupCache.Remove:input_type -> geecachepb.RemoveRequest
+	1, // 3: geecachepb.GroupCache.Get:output_type -> geecachepb.GetResponse
+	1, // 4: geecachepb.GroupCache.BatchGet:output_type -> geecachepb.GetResponse
+	3, // 5: geecachepb.GroupCache.Remove:output_type -> geecachepb.RemoveResponse
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_geecachepb_proto_init() }
+func file_geecachepb_proto_init() {
+	if File_geecachepb_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_geecachepb_proto_rawDesc), len(file_geecachepb_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_geecachepb_proto_goTypes,
+		DependencyIndexes: file_geecachepb_proto_depIdxs,
+		MessageInfos:      file_geecachepb_proto_msgTypes,
+	}.Build()
+	File_geecachepb_proto = out.File
+	file_geecachepb_proto_goTypes = nil
+	file_geecachepb_proto_depIdxs = nil
+}
@@ -0,0 +1,198 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: geecachepb.proto
+
+package geecachepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GroupCache_Get_FullMethodName      = "/geecachepb.GroupCache/Get"
+	GroupCache_BatchGet_FullMethodName = "/geecachepb.GroupCache/BatchGet"
+	GroupCache_Remove_FullMethodName   = "/geecachepb.GroupCache/Remove"
+)
+
+// GroupCacheClient is the client API for GroupCache service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// GroupCache 是gRPC传输方式下节点之间的服务
+type GroupCacheClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// BatchGet 按请求的顺序依次返回每个key的结果
+	BatchGet(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[GetRequest, GetResponse], error)
+	Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error)
+}
+
+type groupCacheClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGroupCacheClient(cc grpc.ClientConnInterface) GroupCacheClient {
+	return &groupCacheClient{cc}
+}
+
+func (c *groupCacheClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, GroupCache_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *groupCacheClient) BatchGet(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[GetRequest, GetResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GroupCache_ServiceDesc.Streams[0], GroupCache_BatchGet_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetRequest, GetResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GroupCache_BatchGetClient = grpc.BidiStreamingClient[GetRequest, GetResponse]
+
+func (c *groupCacheClient) Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveResponse)
+	err := c.cc.Invoke(ctx, GroupCache_Remove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GroupCacheServer is the server API for GroupCache service.
+// All implementations must embed UnimplementedGroupCacheServer
+// for forward compatibility.
+//
+// GroupCache 是gRPC传输方式下节点之间的服务
+type GroupCacheServer interface {
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// BatchGet 按请求的顺序依次返回每个key的结果
+	BatchGet(grpc.BidiStreamingServer[GetRequest, GetResponse]) error
+	Remove(context.Context, *RemoveRequest) (*RemoveResponse, error)
+	mustEmbedUnimplementedGroupCacheServer()
+}
+
+// UnimplementedGroupCacheServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGroupCacheServer struct{}
+
+func (UnimplementedGroupCacheServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedGroupCacheServer) BatchGet(grpc.BidiStreamingServer[GetRequest, GetResponse]) error {
+	return status.Error(codes.Unimplemented, "method BatchGet not implemented")
+}
+func (UnimplementedGroupCacheServer) Remove(context.Context, *RemoveRequest) (*RemoveResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedGroupCacheServer) mustEmbedUnimplementedGroupCacheServer() {}
+func (UnimplementedGroupCacheServer) testEmbeddedByValue()                    {}
+
+// UnsafeGroupCacheServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GroupCacheServer will
+// result in compilation errors.
+type UnsafeGroupCacheServer interface {
+	mustEmbedUnimplementedGroupCacheServer()
+}
+
+func RegisterGroupCacheServer(s grpc.ServiceRegistrar, srv GroupCacheServer) {
+	// If the following call panics, it indicates UnimplementedGroupCacheServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GroupCache_ServiceDesc, srv)
+}
+
+func _GroupCache_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GroupCache_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GroupCache_BatchGet_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GroupCacheServer).BatchGet(&grpc.GenericServerStream[GetRequest, GetResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GroupCache_BatchGetServer = grpc.BidiStreamingServer[GetRequest, GetResponse]
+
+func _GroupCache_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GroupCacheServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GroupCache_Remove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GroupCacheServer).Remove(ctx, req.(*RemoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GroupCache_ServiceDesc is the grpc.ServiceDesc for GroupCache service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GroupCache_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "geecachepb.GroupCache",
+	HandlerType: (*GroupCacheServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _GroupCache_Get_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _GroupCache_Remove_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchGet",
+			Handler:       _GroupCache_BatchGet_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "geecachepb.proto",
+}
@@ -0,0 +1,5 @@
+// Package geecachepb 定义了节点之间通信使用的protobuf消息和gRPC服务
+// Package geecachepb holds the protobuf messages and gRPC service used between peers
+package geecachepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative geecachepb.proto
@@ -0,0 +1,325 @@
+package geecache
+
+import (
+	"LinJz_gee_cache/geecache/consistenthash"
+	pb "LinJz_gee_cache/geecache/geecachepb"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// GRPCPool 与HTTPPool类似，只是节点之间使用gRPC通信
+// 作为客户端，它实现了PeerPicker接口，同样使用一致性哈希算法选择节点，每个远程节点对应一个grpcGetter
+// 作为服务端，通过Register把GroupCache服务注册到grpc.Server上
+// GRPCPool implements PeerPicker for a pool of gRPC peers
+type GRPCPool struct {
+	// this peer's address, e.g. "10.0.0.2:8008"
+	self        string
+	opts        GRPCPoolOptions
+	mu          sync.Mutex // guards peers and grpcGetters
+	peers       consistenthash.Picker
+	grpcGetters map[string]*grpcGetter // keyed by e.g. "10.0.0.2:8008"
+}
+
+// GRPCPoolOptions 是GRPCPool的可选配置，零值表示使用默认值
+// GRPCPoolOptions are the configurations of a GRPCPool
+type GRPCPoolOptions struct {
+	// Replicas 虚拟节点倍数，默认为defaultReplicas
+	Replicas int
+	// HashFn 一致性哈希使用的哈希函数，默认为crc32.ChecksumIEEE
+	HashFn consistenthash.Hash
+	// Placement key放置算法，默认为consistenthash.Ring，使用consistenthash.Jump时与HTTPPoolOptions.Placement一样要求所有节点以相同的顺序设置节点
+	Placement consistenthash.Placement
+	// HashTags 开启后只按consistenthash.HashTag(key)选择节点，与HTTPPoolOptions.HashTags相同
+	HashTags bool
+	// DialOptions 创建到远程节点连接时使用的选项，默认不使用TLS
+	DialOptions []grpc.DialOption
+}
+
+// NewGRPCPool initializes a gRPC pool of peers
+func NewGRPCPool(self string, o *GRPCPoolOptions) *GRPCPool {
+	p := &GRPCPool{self: self}
+	if o != nil {
+		p.opts = *o
+	}
+	if p.opts.Replicas == 0 {
+		p.opts.Replicas = defaultReplicas
+	}
+	if len(p.opts.DialOptions) == 0 {
+		p.opts.DialOptions = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	return p
+}
+
+// Log info with server name
+func (p *GRPCPool) Log(format string, v ...any) {
+	log.Printf("[Server %s] %s", p.self, fmt.Sprintf(format, v...))
+}
+
+// Register 把GroupCache服务注册到s上，s负责监听端口
+// Register registers the pool's GroupCache service on s
+func (p *GRPCPool) Register(s grpc.ServiceRegistrar) {
+	pb.RegisterGroupCacheServer(s, &grpcServer{pool: p})
+}
+
+// Set 与HTTPPool.Set相同，重新构建哈希环，并为除本节点外的每一个节点创建grpcGetter，旧的连接会被关闭
+// 所有节点必须以相同的顺序传入peers
+// Set updates the pool's list of peers
+func (p *GRPCPool) Set(peers ...string) error {
+	getters := make(map[string]*grpcGetter, len(peers))
+	for _, peer := range peers {
+		if peer == p.self { // 本节点的key在本地加载，不需要连接
+			continue
+		}
+		conn, err := grpc.NewClient(peer, p.opts.DialOptions...)
+		if err != nil {
+			for _, g := range getters {
+				g.conn.Close()
+			}
+			return fmt.Errorf("dial %s: %v", peer, err)
+		}
+		getters[peer] = &grpcGetter{conn: conn, client: pb.NewGroupCacheClient(conn)}
+	}
+
+	p.mu.Lock()
+	old := p.grpcGetters
+	p.peers = consistenthash.NewPicker(p.opts.Placement, p.opts.Replicas, p.opts.HashFn)
+	p.peers.Add(peers...)
+	p.grpcGetters = getters
+	p.mu.Unlock()
+
+	for _, g := range old {
+		g.conn.Close()
+	}
+	return nil
+}
+
+// PickPeer picks a peer according to key
+func (p *GRPCPool) PickPeer(key string) (PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil, false
+	}
+	if peer := p.peers.Get(p.placementKey(key)); peer != "" && peer != p.self {
+		p.Log("Pick peer %s", peer)
+		return p.grpcGetters[peer], true
+	}
+	return nil, false
+}
+
+// PickPeers 按照哈希环上的顺序返回key的所属节点及其后继节点，最多n个，遇到本节点时停止
+// PickPeers picks the owner of key and its successors
+func (p *GRPCPool) PickPeers(key string, n int) []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil
+	}
+	var getters []PeerGetter
+	for _, peer := range p.peers.GetN(p.placementKey(key), n) {
+		if peer == p.self {
+			break
+		}
+		getters = append(getters, p.grpcGetters[peer])
+	}
+	return getters
+}
+
+// PickOwners GRPCPool没有熔断和负载统计，与PickPeers相同
+// PickOwners picks the owner of key and its successors
+func (p *GRPCPool) PickOwners(key string, n int) []PeerGetter {
+	return p.PickPeers(key, n)
+}
+
+// Peers 按名称顺序返回所有的远程节点
+// Peers returns all peers except self
+func (p *GRPCPool) Peers() []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.grpcGetters))
+	for peer := range p.grpcGetters {
+		names = append(names, peer)
+	}
+	sort.Strings(names)
+	getters := make([]PeerGetter, len(names))
+	for i, peer := range names {
+		getters[i] = p.grpcGetters[peer]
+	}
+	return getters
+}
+
+// placementKey 返回选择节点时使用的key
+func (p *GRPCPool) placementKey(key string) string {
+	if p.opts.HashTags {
+		return consistenthash.HashTag(key)
+	}
+	return key
+}
+
+// Close 关闭所有到远程节点的连接
+// Close closes the connections to all peers
+func (p *GRPCPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for _, g := range p.grpcGetters {
+		errs = append(errs, g.conn.Close())
+	}
+	p.grpcGetters = nil
+	p.peers = nil
+	return errors.Join(errs...)
+}
+
+// grpcServer 是GroupCache服务的服务端实现，收到请求后在本节点的Group中查找
+type grpcServer struct {
+	pb.UnimplementedGroupCacheServer
+	pool *GRPCPool
+}
+
+func (s *grpcServer) Get(ctx context.Context, in *pb.GetRequest) (*pb.GetResponse, error) {
+	s.pool.Log("Get %s/%s", in.GetGroup(), in.GetKey())
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.stats.serverRequest()
+	res, err := group.servePeer(ctx, string(in.GetKey()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return res, nil
+}
+
+// BatchGet 依次处理流中的每一个请求，单个key失败时把错误写入响应的Error字段，流继续处理后面的请求
+func (s *grpcServer) BatchGet(stream grpc.BidiStreamingServer[pb.GetRequest, pb.GetResponse]) error {
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.pool.Log("BatchGet %s/%s", in.GetGroup(), in.GetKey())
+		res := &pb.GetResponse{}
+		if group := GetGroup(in.GetGroup()); group == nil {
+			res.Error = "no such group " + in.GetGroup()
+		} else {
+			group.stats.serverRequest()
+			if res, err = group.servePeer(stream.Context(), string(in.GetKey())); err != nil {
+				res = &pb.GetResponse{Error: err.Error()}
+			}
+		}
+		if err = stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *grpcServer) Remove(ctx context.Context, in *pb.RemoveRequest) (*pb.RemoveResponse, error) {
+	s.pool.Log("Remove %s/%s", in.GetGroup(), in.GetKey())
+	group, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.stats.serverRequest()
+	group.removeLocally(string(in.GetKey()))
+	return &pb.RemoveResponse{}, nil
+}
+
+func (s *grpcServer) group(name string) (*Group, error) {
+	group := GetGroup(name)
+	if group == nil {
+		return nil, status.Error(codes.NotFound, "no such group "+name)
+	}
+	return group, nil
+}
+
+// grpcGetter 是gRPC的客户端，实现了PeerGetter接口，每一个远程节点对应一个grpcGetter
+type grpcGetter struct {
+	conn   *grpc.ClientConn
+	client pb.GroupCacheClient
+}
+
+// Get 从远程节点获取缓存值
+func (g *grpcGetter) Get(group string, key string) ([]byte, error) {
+	return g.GetContext(context.Background(), group, key)
+}
+
+// GetContext 与Get相同，但请求会带上ctx
+func (g *grpcGetter) GetContext(ctx context.Context, group string, key string) ([]byte, error) {
+	out := &pb.GetResponse{}
+	if err := g.GetProto(ctx, &pb.GetRequest{Group: group, Key: []byte(key)}, out); err != nil {
+		return nil, err
+	}
+	return out.Value, nil
+}
+
+// GetProto 从远程节点获取缓存值以及过期时间等元数据
+func (g *grpcGetter) GetProto(ctx context.Context, in *pb.GetRequest, out *pb.GetResponse) error {
+	res, err := g.client.Get(ctx, in)
+	if err != nil {
+		return err
+	}
+	out.Reset()
+	proto.Merge(out, res)
+	return nil
+}
+
+// GetBatch 通过一个双向流查询多个key，结果按keys的顺序返回
+func (g *grpcGetter) GetBatch(ctx context.Context, group string, keys []string) ([]*pb.GetResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := g.client.BatchGet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 一边发送请求一边接收响应，避免请求太多时双方的缓冲区被写满
+	sendErr := make(chan error, 1)
+	go func() {
+		for _, key := range keys {
+			if err := stream.Send(&pb.GetRequest{Group: group, Key: []byte(key)}); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- stream.CloseSend()
+	}()
+
+	results := make([]*pb.GetResponse, 0, len(keys))
+	for range keys {
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	if err := <-sendErr; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Remove 通知远程节点删除key对应的缓存
+func (g *grpcGetter) Remove(ctx context.Context, group string, key string) error {
+	_, err := g.client.Remove(ctx, &pb.RemoveRequest{Group: group, Key: []byte(key)})
+	return err
+}
+
+var _ BatchPeerGetter = (*grpcGetter)(nil)
+var _ ProtoPeerGetter = (*grpcGetter)(nil)
+var _ ContextPeerGetter = (*grpcGetter)(nil)
+var _ PeerListPicker = (*GRPCPool)(nil)
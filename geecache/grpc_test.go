@@ -0,0 +1,117 @@
+package geecache
+
+import (
+	pb "LinJz_gee_cache/geecache/geecachepb"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newBufconnPool 启动名为peer的内存gRPC服务端，并返回一个只认识该节点的客户端GRPCPool，测试不需要真正的网络
+func newBufconnPool(t *testing.T, peer string) *GRPCPool {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	NewGRPCPool(peer, nil).Register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	client := NewGRPCPool("self", &GRPCPoolOptions{
+		DialOptions: []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+		},
+	})
+	if err := client.Set("passthrough:///" + peer); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestGRPCPool(t *testing.T) {
+	loads := 0
+	gee := NewGroup("grpc", 2<<10, TTLGetterFunc(
+		func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+			loads++
+			if v, ok := db[key]; ok {
+				return []byte(v), time.Hour, nil
+			}
+			return nil, 0, fmt.Errorf("%s not exist", key)
+		}))
+	client := newBufconnPool(t, "bufnet")
+
+	peer, ok := client.PickPeer("Tom")
+	if !ok {
+		t.Fatal("Tom should be owned by the remote peer")
+	}
+	out := &pb.GetResponse{}
+	if err := peer.(ProtoPeerGetter).GetProto(context.Background(), &pb.GetRequest{Group: "grpc", Key: []byte("Tom")}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != db["Tom"] || out.GetExpire() == 0 {
+		t.Fatalf("GetProto = %v", out)
+	}
+	if _, err := peer.Get("grpc", "unknown"); err == nil {
+		t.Fatal("unknown should fail")
+	}
+
+	if err := peer.Remove(context.Background(), "grpc", "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gee.mainCache.get("Tom"); ok {
+		t.Fatal("Tom should be removed from the remote peer")
+	}
+}
+
+// Set不会为本节点创建连接
+func TestGRPCPoolSetSelf(t *testing.T) {
+	p := NewGRPCPool("self:8001", nil)
+	if err := p.Set("self:8001", "peer:8002"); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, ok := p.grpcGetters["self:8001"]; ok || len(p.grpcGetters) != 1 || len(p.Peers()) != 1 {
+		t.Fatalf("getters = %v, expect only peer:8002", p.grpcGetters)
+	}
+}
+
+func TestGRPCPoolBatchGet(t *testing.T) {
+	NewGroup("grpc-batch", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+	client := newBufconnPool(t, "bufnet")
+
+	peer, _ := client.PickPeer("Tom")
+	keys := []string{"Tom", "unknown", "Jack", "Sam"}
+	results, err := peer.(BatchPeerGetter).GetBatch(context.Background(), "grpc-batch", keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(keys) {
+		t.Fatalf("got %d results, expect %d", len(results), len(keys))
+	}
+	for i, key := range keys {
+		if key == "unknown" {
+			if results[i].GetError() == "" {
+				t.Fatal("unknown should fail")
+			}
+			continue
+		}
+		if string(results[i].GetValue()) != db[key] || results[i].GetError() != "" {
+			t.Fatalf("result of %s = %v", key, results[i])
+		}
+	}
+}
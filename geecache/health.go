@@ -0,0 +1,154 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	healthPath              = "_health"
+	defaultFailureThreshold = 3
+	defaultBreakerCooldown  = 5 * time.Second
+)
+
+// breakerState 是熔断器的状态
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 正常访问
+	breakerOpen                         // 连续失败次数达到阈值，暂停访问该节点
+	breakerHalfOpen                     // 冷却时间已过，允许访问，再失败一次就重新熔断
+)
+
+// circuitBreaker 是每个远程节点的熔断器
+// 连续失败threshold次后熔断，熔断期间PickPeer会跳过该节点，它负责的key由后继节点或者本节点加载
+// 熔断cooldown之后进入半开状态，或者健康检查成功后直接恢复
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time // 测试时替换
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// available 返回是否可以访问该节点
+func (b *circuitBreaker) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.state = breakerHalfOpen
+	}
+	return b.state != breakerOpen
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// report 根据一次请求的结果更新熔断器
+// 调用者自己取消的请求不算作节点的失败，但超时算作失败：节点迟迟没有响应和节点不可达一样，都应该让请求尽快转向其他节点
+// 加载在singleflight中执行时，所有调用者都超时后ctx才会被取消，ctx.Err()是context.Canceled，取消的原因记录在context.Cause(ctx)中
+func (b *circuitBreaker) report(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		b.success()
+	case errors.Is(context.Cause(ctx), context.Canceled):
+	default:
+		b.failure()
+	}
+}
+
+// serveHealth 响应健康检查请求
+func (p *HTTPPool) serveHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok"))
+}
+
+// probe 访问远程节点的健康检查接口
+func (h *httpGetter) probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
+
+// healthCheck 每隔interval对所有远程节点做一次健康检查，直到p.Close()被调用
+func (p *HTTPPool) healthCheck(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.checkPeers(interval)
+		}
+	}
+}
+
+// checkPeers 并发地探测所有远程节点，每个探测最多等待timeout
+func (p *HTTPPool) checkPeers(timeout time.Duration) {
+	p.mu.Lock()
+	getters := make(map[string]*httpGetter, len(p.httpGetters))
+	for peer, getter := range p.httpGetters {
+		if peer != p.self {
+			getters[peer] = getter
+		}
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for peer, getter := range getters {
+		wg.Add(1)
+		go func(peer string, getter *httpGetter) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := getter.probe(ctx); err != nil {
+				p.Log("health check %s failed: %v", peer, err)
+				getter.breaker.failure()
+				return
+			}
+			getter.breaker.success()
+		}(peer, getter)
+	}
+	wg.Wait()
+}
+
+// Close 停止健康检查
+// Close stops the background health checker
+func (p *HTTPPool) Close() {
+	p.closeOnce.Do(func() { close(p.done) })
+}
@@ -0,0 +1,133 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitFor 每隔1ms检查一次cond，直到cond成立或者超过1秒
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return cond()
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := time.Unix(0, 0)
+	b := newCircuitBreaker(2, 20*time.Millisecond)
+	b.now = func() time.Time { return clock }
+	ctx := context.Background()
+	b.report(ctx, errors.New("connection refused"))
+	if !b.available() {
+		t.Fatal("breaker should stay closed before reaching the threshold")
+	}
+	b.report(ctx, errors.New("connection refused"))
+	if b.available() {
+		t.Fatal("breaker should open after 2 consecutive failures")
+	}
+
+	clock = clock.Add(30 * time.Millisecond)
+	if !b.available() {
+		t.Fatal("breaker should be half-open after cooldown")
+	}
+	b.report(ctx, errors.New("connection refused"))
+	if b.available() {
+		t.Fatal("a failure in half-open state should open the breaker again")
+	}
+
+	// 调用者自己取消的请求不算作节点的失败
+	b = newCircuitBreaker(1, time.Minute)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	b.report(canceled, context.Canceled)
+	if !b.available() {
+		t.Fatal("canceled requests should not open the breaker")
+	}
+
+	// 超时算作节点的失败
+	expired, cancel := context.WithDeadline(ctx, time.Unix(0, 0))
+	defer cancel()
+	b.report(expired, context.DeadlineExceeded)
+	if b.available() {
+		t.Fatal("timed out requests should open the breaker")
+	}
+}
+
+// 测试PickPeer跳过熔断中的节点，并在健康检查成功后恢复
+func TestHTTPPoolHealthCheck(t *testing.T) {
+	srv := httptest.NewServer(NewHTTPPool("peer"))
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("self", &HTTPPoolOptions{
+		HealthCheckInterval: 10 * time.Millisecond,
+		FailureThreshold:    1,
+		BreakerCooldown:     time.Minute,
+	})
+	defer pool.Close()
+	pool.Set(srv.URL)
+
+	if _, ok := pool.PickPeer("Tom"); !ok {
+		t.Fatal("Tom should be owned by the peer")
+	}
+	pool.httpGetters[srv.URL].breaker.failure()
+	if _, ok := pool.PickPeer("Tom"); ok {
+		t.Fatal("open-circuit peer should be skipped")
+	}
+
+	if !waitFor(t, func() bool { _, ok := pool.PickPeer("Tom"); return ok }) { // 等待健康检查
+		t.Fatal("peer should be picked again after a successful health check")
+	}
+
+	srv.Close()
+	if !waitFor(t, func() bool { _, ok := pool.PickPeer("Tom"); return !ok }) {
+		t.Fatal("dead peer should be skipped after a failed health check")
+	}
+}
+
+// 远程节点一直没有响应时，调用者超时放弃的请求也算作节点的失败
+func TestGroupPeerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	pool := NewHTTPPoolOpts("self", &HTTPPoolOptions{FailureThreshold: 1, BreakerCooldown: time.Minute})
+	pool.Set(srv.URL)
+	loaded := make(chan string, 1)
+	gee := NewGroup("hanging", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loaded <- key
+		return []byte(key), nil
+	}))
+	gee.RegisterPeers(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "Tom"); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext error = %v, expect %v", err, context.DeadlineExceeded)
+	}
+	if !waitFor(t, func() bool { _, ok := pool.PickPeer("Tom"); return !ok }) {
+		t.Fatal("peer that timed out should be skipped")
+	}
+	// 没有调用者等待的加载不应该再访问数据源并写入缓存
+	select {
+	case key := <-loaded:
+		t.Fatalf("%s was loaded locally after every caller gave up", key)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if keys := gee.Keys(); len(keys) != 0 {
+		t.Fatalf("Keys() = %v after every caller gave up", keys)
+	}
+}
@@ -2,18 +2,35 @@ package geecache
 
 import (
 	"LinJz_gee_cache/geecache/consistenthash"
+	pb "LinJz_gee_cache/geecache/geecachepb"
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"mime"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 )
 
 const (
 	defaultBasePath = "/_geecache/"
 	defaultReplicas = 50
+	// maxWeightRatio SetWeighted中最大权重与最小权重之比的上限，超过的权重按上限处理，避免虚拟节点数过多
+	maxWeightRatio = 100
+)
+
+// 节点之间通过Content-Type协商响应的格式：客户端在Accept中声明支持protobuf，服务端就返回编码后的pb.GetResponse，否则返回原始的字节
+// 这样旧版本只认识原始字节的节点仍然可以和新版本的节点互相通信
+const (
+	protobufContentType = "application/x-protobuf"
+	rawContentType      = "application/octet-stream"
 )
 
 // HTTPPool 作为承载节点间HTTP通信的核心数据结构（包括服务端和客户端 ）
@@ -24,18 +41,74 @@ const (
 type HTTPPool struct {
 	// this peer's base URL,e.g. "https://example.net:8000"
 	self        string
-	basePath    string
-	mu          sync.Mutex // guards peers and httpGetters
-	peers       *consistenthash.Map
+	opts        HTTPPoolOptions
+	client      *http.Client // 所有httpGetter共用，从而共用连接池
+	mu          sync.Mutex   // guards peers and httpGetters
+	peers       consistenthash.Picker
 	httpGetters map[string]*httpGetter // keyed by e.g. "http://10.0.0.2:8008"
+	done        chan struct{}          // 关闭后健康检查停止
+	closeOnce   sync.Once
+}
+
+// HTTPPoolOptions 是HTTPPool的可选配置，零值表示使用默认值
+// HTTPPoolOptions are the configurations of a HTTPPool
+type HTTPPoolOptions struct {
+	// BasePath 节点间通信地址的前缀，默认为"/_geecache/"
+	BasePath string
+	// Replicas 虚拟节点倍数，默认为defaultReplicas
+	Replicas int
+	// HashFn 一致性哈希使用的哈希函数，默认为crc32.ChecksumIEEE
+	HashFn consistenthash.Hash
+	// Placement key放置算法，默认为consistenthash.Ring
+	// consistenthash.Jump按添加的顺序给节点编号，所有节点调用Set和AddPeers时必须传入相同顺序的节点，例如都按名称排序
+	Placement consistenthash.Placement
+	// HashTags 开启后只按consistenthash.HashTag(key)选择节点，"user:{42}:profile"和"user:{42}:scores"会落在同一个节点上
+	HashTags bool
+	// Transport 访问远程节点使用的http.RoundTripper，可以用来配置连接池，默认为http.DefaultTransport
+	Transport http.RoundTripper
+	// Timeout 访问远程节点的超时时间，0表示不限制，请求仍然受调用者ctx的约束
+	Timeout time.Duration
+	// HealthCheckInterval 对远程节点做健康检查的间隔，0表示不做健康检查，此时熔断的节点在冷却时间之后恢复
+	HealthCheckInterval time.Duration
+	// FailureThreshold 连续失败多少次后熔断该节点，默认为3
+	FailureThreshold int
+	// BreakerCooldown 熔断之后多久允许再次尝试该节点，默认为5秒
+	BreakerCooldown time.Duration
+	// BoundedLoadFactor 大于0时开启有界负载模式，每个远程节点正在处理的请求数不超过平均值的(1+BoundedLoadFactor)倍，
+	// 超过时由哈希环上的下一个节点代替，0表示不限制，只对consistenthash.Ring有效
+	BoundedLoadFactor float64
 }
 
 // NewHTTPPool initializes an HTTP pool of peers
 func NewHTTPPool(self string) *HTTPPool {
-	return &HTTPPool{
-		self:     self,
-		basePath: defaultBasePath,
+	return NewHTTPPoolOpts(self, nil)
+}
+
+// NewHTTPPoolOpts 与NewHTTPPool相同，但可以通过o修改默认配置，o可以为nil
+// NewHTTPPoolOpts initializes an HTTP pool of peers with the given options
+func NewHTTPPoolOpts(self string, o *HTTPPoolOptions) *HTTPPool {
+	p := &HTTPPool{self: self}
+	if o != nil {
+		p.opts = *o
 	}
+	if p.opts.BasePath == "" {
+		p.opts.BasePath = defaultBasePath
+	}
+	if p.opts.Replicas == 0 {
+		p.opts.Replicas = defaultReplicas
+	}
+	if p.opts.FailureThreshold == 0 {
+		p.opts.FailureThreshold = defaultFailureThreshold
+	}
+	if p.opts.BreakerCooldown == 0 {
+		p.opts.BreakerCooldown = defaultBreakerCooldown
+	}
+	p.client = &http.Client{Transport: p.opts.Transport, Timeout: p.opts.Timeout}
+	p.done = make(chan struct{})
+	if p.opts.HealthCheckInterval > 0 {
+		go p.healthCheck(p.opts.HealthCheckInterval)
+	}
+	return p
 }
 
 // Log info with server name
@@ -45,21 +118,36 @@ func (p *HTTPPool) Log(format string, v ...any) {
 
 // ServeHTTP 的实现逻辑比较简单，首先判断访问路径的前缀是否是basePath，不是返回错误，注意，r.URL.Path是端口后面的那一段，r.URL还有一个字段是Host，保存的是host or host:port，所以只需要拿HTTPPool的basePath去比较就可以，不用拿self去比较
 // 我们约定访问路径格式为/<basepath>/<groupname>/<key>，通过groupname得到group实例，再使用group.Get(key)获取缓存数据，最后使用w.Write()将缓存值作为httpResponse的body返回
+// 注意，这里使用的是未解码的r.URL.EscapedPath()，因为r.URL.Path已经把key中的"%2F"解码成了"/"，无法再区分group和key的边界，group和key由escapeSegment编码，在这里分别解码
+// 如果请求方法是DELETE，则删除本节点上key对应的缓存
 // ServeHTTP handle all http requests
 func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if !strings.HasPrefix(r.URL.Path, p.basePath) { // strings.HasPrefix判断r.URL.Path的前缀是否是p.basePath
-		panic("HTTPPool serving unexpected path:" + r.URL.Path)
+	path := r.URL.EscapedPath()
+	if !strings.HasPrefix(path, p.opts.BasePath) { // strings.HasPrefix判断path的前缀是否是p.opts.BasePath
+		panic("HTTPPool serving unexpected path:" + path)
+	}
+	p.Log("%s %s", r.Method, path)
+	if path[len(p.opts.BasePath):] == healthPath {
+		p.serveHealth(w, r)
+		return
 	}
-	p.Log("%s %s", r.Method, r.URL.Path)
 	// /<basepath>/<groupname>/<key> required
-	parts := strings.SplitN(r.URL.Path[len(p.basePath):], "/", 2) // r.URL.Path[len(p.basePath):]返回r.URL.Path从len(p.basePath)开始到len(r.URL.Path.)减1的位置，然后再切成两份变成数组
+	parts := strings.SplitN(path[len(p.opts.BasePath):], "/", 2) // path[len(p.opts.BasePath):]返回path从len(p.opts.BasePath)开始到len(path)减1的位置，然后再切成两份变成数组
 	if len(parts) != 2 {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
-	groupName := parts[0]
-	key := parts[1]
+	groupName, err := url.PathUnescape(parts[0])
+	if err != nil {
+		http.Error(w, "bad group: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	key, err := url.PathUnescape(parts[1])
+	if err != nil {
+		http.Error(w, "bad key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	group := GetGroup(groupName)
 	if group == nil {
@@ -67,78 +155,403 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	view, err := group.Get(key)
+	group.stats.serverRequest()
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		group.removeLocally(key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	res, err := group.servePeer(r.Context(), key)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/octet-stream")
-	_, err = w.Write(view.ByteSlice()) // view是ByteView类型的，而w.Write需要byte[]类型的，所以给它转换成byte[]类型
+	body := res.GetValue()
+	contentType := rawContentType
+	if acceptsProtobuf(r) {
+		if body, err = proto.Marshal(res); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		contentType = protobufContentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, err = w.Write(body)
 	if err != nil {
 		http.Error(w, "response write error", http.StatusInternalServerError)
 		return
 	}
 }
 
+// acceptsProtobuf 判断客户端是否在Accept中声明了支持protobuf格式
+func acceptsProtobuf(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		if t, _, err := mime.ParseMediaType(strings.TrimSpace(v)); err == nil && t == protobufContentType {
+			return true
+		}
+	}
+	return false
+}
+
 // 在GeeCache第三天，我们为HTTPPool实现了服务端功能，但通信不仅需要服务端还需要客户端，所以，接下来就要为HTTPPool实现客户端功能
 // 首先创建具体的HTTP客户端类httpGetter，实现PeerGetter接口
 // baseURL表示将要访问的远程节点的地址，例如http://example.com/_geecache/
+// client是HTTPPool中共用的http.Client
+// pool和peer用于在有界负载模式下登记该节点正在处理的请求，为nil时不登记
+// latency记录访问该节点的耗时，由MetricsHandler输出
+// breaker是该节点的熔断器，请求和健康检查的结果都会更新它
 type httpGetter struct {
 	baseURL string
+	client  *http.Client
+	latency *histogram
+	breaker *circuitBreaker
+	pool    *HTTPPool
+	peer    string
+}
+
+// url 返回访问远程节点上group中key的地址，格式为<baseURL><group>/<key>
+func (h *httpGetter) url(group string, key string) string {
+	return h.baseURL + escapeSegment(group) + "/" + escapeSegment(key)
+}
+
+// escapeSegment 把s编码成URL路径中的一段，ServeHTTP用url.PathUnescape解码
+// 之前使用的url.QueryEscape会把空格编码成"+"，而服务端按路径解码时不会把"+"还原成空格，key中的"/"也会被当成group和key的分隔符
+// url.PathEscape会编码"/"、"%"、"+"、空格以及任意非ASCII字节，此外"."和".."会被http.ServeMux当作相对路径清理掉，所以也需要编码
+func escapeSegment(s string) string {
+	switch s {
+	case ".":
+		return "%2E"
+	case "..":
+		return "%2E%2E"
+	}
+	return url.PathEscape(s)
 }
 
 // Get 使用http.Get()方式获取返回值，并转换为[]bytes类型
 func (h *httpGetter) Get(group string, key string) ([]byte, error) {
-	u := fmt.Sprintf(
-		"%v%v/%v",
-		h.baseURL,
-		url.QueryEscape(group),
-		url.QueryEscape(key))
-	res, err := http.Get(u) // 调用http.Get()会直接来到ServeHTTP()，因为在main.go中startCacheServer()的http.ListenAndServe(addr[7:], peers)传递了处理函数接口为HTTPPool，而HTTPPool中的处理函数就是ServeHTTP()
-	if err != nil {
+	return h.GetContext(context.Background(), group, key)
+}
+
+// GetContext 与Get相同，但请求会带上ctx，ctx被取消或者超时时请求也会被中断
+func (h *httpGetter) GetContext(ctx context.Context, group string, key string) ([]byte, error) {
+	out := &pb.GetResponse{}
+	if err := h.GetProto(ctx, &pb.GetRequest{Group: group, Key: []byte(key)}, out); err != nil {
 		return nil, err
 	}
+	return out.Value, nil
+}
+
+// GetProto 向远程节点请求缓存值，并通过Accept声明支持protobuf格式
+// 如果远程节点返回的是protobuf格式，则解码得到过期时间等元数据；如果是旧版本节点返回的原始字节，则只填充out.Value
+func (h *httpGetter) GetProto(ctx context.Context, in *pb.GetRequest, out *pb.GetResponse) error {
+	defer h.begin()()
+	res, err := h.do(ctx, http.MethodGet, in.GetGroup(), string(in.GetKey()))
+	if err != nil {
+		return err
+	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned: %v", res.Status)
+		return fmt.Errorf("server returned: %v", res.Status)
 	}
 
 	bytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading response body: %v", err)
+		return fmt.Errorf("reading response body: %v", err)
+	}
+
+	if t, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); t == protobufContentType {
+		if err = proto.Unmarshal(bytes, out); err != nil {
+			return fmt.Errorf("decoding response body: %v", err)
+		}
+		return nil
+	}
+	out.Reset()
+	out.Value = bytes
+	return nil
+}
+
+// Remove 向远程节点发送DELETE请求，删除远程节点上key对应的缓存
+func (h *httpGetter) Remove(ctx context.Context, group string, key string) error {
+	defer h.begin()()
+	res, err := h.do(ctx, http.MethodDelete, group, key)
+	if err != nil {
+		return err
 	}
+	defer res.Body.Close()
 
-	return bytes, nil
+	if res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
+
+// begin 在有界负载模式下登记一个发往该节点的请求，返回的函数在请求结束（响应体读取完毕）后调用，释放该请求占用的负载
+func (h *httpGetter) begin() (done func()) {
+	if h.pool == nil {
+		return func() {}
+	}
+	h.pool.mu.Lock()
+	defer h.pool.mu.Unlock()
+	peers, ok := h.pool.boundedLocked()
+	if !ok {
+		return func() {}
+	}
+	peers.Inc(h.peer)
+	return func() {
+		h.pool.mu.Lock()
+		peers.Done(h.peer)
+		h.pool.mu.Unlock()
+	}
+}
+
+// do 向远程节点发送请求，记录耗时，并根据结果更新熔断器
+// 只有请求没有发出去或者没有收到响应才算作节点的失败，服务端返回的错误（例如key不存在）说明节点仍然可用
+func (h *httpGetter) do(ctx context.Context, method string, group string, key string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.url(group, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Accept", protobufContentType+", "+rawContentType)
+	}
+	start := time.Now()
+	res, err := h.client.Do(req) // 发出的请求会直接来到远程节点的ServeHTTP()，因为在main.go中startCacheServer()的http.ListenAndServe(addr[7:], peers)传递了处理函数接口为HTTPPool，而HTTPPool中的处理函数就是ServeHTTP()
+	h.latency.observe(time.Since(start))
+	h.breaker.report(ctx, err)
+	return res, err
 }
 
 // Set 方法实例化了一致性哈希算法，并且添加了传入的节点，并且为每一个节点创建了一个HTTP客户端httpGetter
 // Set 第三步，实现PeerPicker接口
+// 已经存在的节点会复用原来的httpGetter，保留它的熔断器和耗时统计
+// 所有节点必须以相同的顺序传入peers，否则使用consistenthash.Jump时各个节点对key的归属看法不一致
 // Set updates the pool's list of peers
 func (p *HTTPPool) Set(peers ...string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.peers = consistenthash.New(defaultReplicas, nil)
+	p.peers = p.newMap()
 	p.peers.Add(peers...)
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
+	getters := make(map[string]*httpGetter, len(peers))
+	for _, peer := range peers {
+		getters[peer] = p.getterLocked(peer)
+	}
+	p.httpGetters = getters
+}
+
+// AddPeers 在哈希环上增加节点，只有新增节点负责的key会改变归属，已有节点的httpGetter保持不变
+// AddPeers adds peers to the pool
+func (p *HTTPPool) AddPeers(peers ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		p.peers = p.newMap()
+		p.httpGetters = make(map[string]*httpGetter, len(peers))
+	}
+	p.peers.Add(peers...)
+	for _, peer := range peers {
+		p.httpGetters[peer] = p.getterLocked(peer)
+	}
+}
+
+// SetWeighted 与Set类似，但每个节点负责的key的比例与它的权重成正比，例如可以把权重设置为各个节点的cacheBytes
+// 权重最小的节点拥有Replicas个虚拟节点，其余节点按权重的比例相应增加，权重之比最多为maxWeightRatio
+// 权重必须大于0，否则返回错误，节点列表保持不变
+// 如果Placement不支持权重（Jump和Maglev），则忽略权重，节点按名称顺序添加
+// SetWeighted updates the pool's list of peers with their relative weights
+func (p *HTTPPool) SetWeighted(peers map[string]int64) error {
+	var min int64
+	names := make([]string, 0, len(peers))
+	for peer, w := range peers {
+		if w <= 0 {
+			return fmt.Errorf("weight of %s must be positive, got %d", peer, w)
+		}
+		if min == 0 || w < min {
+			min = w
+		}
+		names = append(names, peer)
+	}
+	sort.Strings(names)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.peers = p.newMap()
+	weighted, ok := p.peers.(consistenthash.WeightedPicker)
+	getters := make(map[string]*httpGetter, len(peers))
+	for _, peer := range names {
+		if ok {
+			weighted.AddWeighted(peer, math.Min(float64(peers[peer])/float64(min), maxWeightRatio))
+		} else {
+			p.peers.Add(peer)
+		}
+		getters[peer] = p.getterLocked(peer)
+	}
+	p.httpGetters = getters
+	return nil
+}
+
+// RemovePeers 从哈希环上删除节点，它们负责的key会落到后继节点上
+// RemovePeers removes peers from the pool
+func (p *HTTPPool) RemovePeers(peers ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return
+	}
+	p.peers.Remove(peers...)
 	for _, peer := range peers {
-		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath}
+		delete(p.httpGetters, peer)
+	}
+}
+
+// newMap 按照配置新建一个Picker
+func (p *HTTPPool) newMap() consistenthash.Picker {
+	picker := consistenthash.NewPicker(p.opts.Placement, p.opts.Replicas, p.opts.HashFn)
+	if m, ok := picker.(*consistenthash.Map); ok && p.opts.BoundedLoadFactor > 0 {
+		m.SetEpsilon(p.opts.BoundedLoadFactor)
+		m.SetLocal(p.self)
+	}
+	return picker
+}
+
+// boundedLocked 在开启了有界负载模式时返回记录负载的哈希环，调用者需要持有p.mu
+func (p *HTTPPool) boundedLocked() (*consistenthash.Map, bool) {
+	if p.opts.BoundedLoadFactor <= 0 {
+		return nil, false
+	}
+	m, ok := p.peers.(*consistenthash.Map)
+	return m, ok
+}
+
+// getterLocked 返回peer已有的httpGetter，没有则新建一个，调用者需要持有p.mu
+func (p *HTTPPool) getterLocked(peer string) *httpGetter {
+	if getter, ok := p.httpGetters[peer]; ok {
+		return getter
+	}
+	return &httpGetter{
+		baseURL: peer + p.opts.BasePath,
+		client:  p.client,
+		latency: newHistogram(),
+		breaker: newCircuitBreaker(p.opts.FailureThreshold, p.opts.BreakerCooldown),
+		pool:    p,
+		peer:    peer,
 	}
 }
 
 // PickPeer 包装了一致性哈希算法的Get()方法，根据具体的key，选择节点，返回节点对应的HTTP客户端
+// 熔断中的节点会被跳过，由它在哈希环上的下一个可用节点代替，如果轮到了本节点，则返回false，从本地加载
 // PickPeer picks a peer according to key
 func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
-		p.Log("Pick peer %s", peer)
-		return p.httpGetters[peer], true
+	if getters := p.pickLocked(key, 1); len(getters) > 0 {
+		p.Log("Pick peer %s", getters[0].baseURL)
+		return getters[0], true
 	}
 	return nil, false
 }
 
+// PickPeers 按照哈希环上的顺序返回key的所属节点及其后继节点，最多n个，跳过熔断中的节点，遇到本节点时停止
+// PickPeers picks the owner of key and its successors
+func (p *HTTPPool) PickPeers(key string, n int) []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var getters []PeerGetter
+	for _, getter := range p.pickLocked(key, n) {
+		getters = append(getters, getter)
+	}
+	return getters
+}
+
+// PickOwners 按照哈希环上的顺序返回key的所属节点及其后继节点，最多n个，遇到本节点时停止，不考虑熔断和负载
+// PickOwners picks the owner of key and its successors, healthy or not
+func (p *HTTPPool) PickOwners(key string, n int) []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil
+	}
+	var getters []PeerGetter
+	for _, peer := range p.peers.GetN(p.placementKey(key), n) {
+		if peer == p.self {
+			break
+		}
+		getters = append(getters, p.httpGetters[peer])
+	}
+	return getters
+}
+
+// Peers 按名称顺序返回所有的远程节点
+// Peers returns all peers except self
+func (p *HTTPPool) Peers() []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.httpGetters))
+	for peer := range p.httpGetters {
+		if peer != p.self {
+			names = append(names, peer)
+		}
+	}
+	sort.Strings(names)
+	getters := make([]PeerGetter, len(names))
+	for i, peer := range names {
+		getters[i] = p.httpGetters[peer]
+	}
+	return getters
+}
+
+// placementKey 返回选择节点时使用的key
+func (p *HTTPPool) placementKey(key string) string {
+	if p.opts.HashTags {
+		return consistenthash.HashTag(key)
+	}
+	return key
+}
+
+// pickLocked 沿着哈希环返回key的所属节点及其后继节点中最多n个可用的远程节点，调用者需要持有p.mu
+// 有界负载模式下，负载已经达到上限的节点也会被跳过；本节点的负载不做统计，轮到本节点时仍然从本地加载
+func (p *HTTPPool) pickLocked(key string, n int) []*httpGetter {
+	if p.peers == nil {
+		return nil
+	}
+	ring, bounded := p.boundedLocked()
+	var getters []*httpGetter
+	key = p.placementKey(key)
+	// 先只取n+1个节点（其中可能有本节点），被跳过的节点太多时再成倍地多取，避免每次都对所有节点计算GetN
+	// GetN(key, m)总是GetN(key, 2m)的前缀，所以只需要检查新增的节点
+	for start, want := 0, n+1; ; start, want = want, want*2 {
+		peers := p.peers.GetN(key, want)
+		for _, peer := range peers[start:] {
+			if peer == p.self || len(getters) == n {
+				return getters
+			}
+			if bounded && ring.Overloaded(peer) {
+				continue
+			}
+			if getter := p.httpGetters[peer]; getter.breaker.available() {
+				getters = append(getters, getter)
+			}
+		}
+		if len(peers) < want { // 已经检查了所有节点
+			break
+		}
+	}
+	return getters
+}
+
 // 这两个的作用是确保这个类型实现了这个接口 如果没有实现会报错的
-var _ PeerGetter = (*httpGetter)(nil)
-var _ PeerPicker = (*HTTPPool)(nil)
+var _ ContextPeerGetter = (*httpGetter)(nil)
+var _ ProtoPeerGetter = (*httpGetter)(nil)
+var _ PeerListPicker = (*HTTPPool)(nil)
+var _ OwnerPicker = (*HTTPPool)(nil)
+var _ PeerLister = (*HTTPPool)(nil)
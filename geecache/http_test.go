@@ -0,0 +1,455 @@
+package geecache
+
+import (
+	"LinJz_gee_cache/geecache/consistenthash"
+	pb "LinJz_gee_cache/geecache/geecachepb"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestPeer 启动一个承载HTTPPool的测试服务器，并返回访问它的httpGetter
+func newTestPeer(t *testing.T) *httpGetter {
+	t.Helper()
+	srv := httptest.NewServer(NewHTTPPool("self"))
+	t.Cleanup(srv.Close)
+	return newTestGetter(srv.URL+defaultBasePath, http.DefaultClient)
+}
+
+func newTestGetter(baseURL string, client *http.Client) *httpGetter {
+	return &httpGetter{
+		baseURL: baseURL,
+		client:  client,
+		latency: newHistogram(),
+		breaker: newCircuitBreaker(defaultFailureThreshold, defaultBreakerCooldown),
+	}
+}
+
+// 测试protobuf协议能够把过期时间传给请求方
+func TestHTTPGetterProtobuf(t *testing.T) {
+	NewGroup("proto", 2<<10, TTLGetterFunc(
+		func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+			return []byte(key), time.Hour, nil
+		}))
+	getter := newTestPeer(t)
+
+	out := &pb.GetResponse{}
+	if err := getter.GetProto(context.Background(), &pb.GetRequest{Group: "proto", Key: []byte("Tom")}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != "Tom" {
+		t.Fatalf("value = %q, expect Tom", out.GetValue())
+	}
+	if expire := time.Unix(0, out.GetExpire()); time.Until(expire) <= 0 || time.Until(expire) > time.Hour {
+		t.Fatalf("expire = %v, expect within an hour", expire)
+	}
+}
+
+// 测试新旧两种格式的节点可以互相通信
+func TestHTTPGetterRaw(t *testing.T) {
+	NewGroup("raw", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	getter := newTestPeer(t)
+
+	// 旧版本的客户端不发送Accept，得到原始字节
+	res, err := http.Get(getter.baseURL + "raw/Tom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if ct := res.Header.Get("Content-Type"); ct != rawContentType || string(body) != "Tom" {
+		t.Fatalf("raw response = %q (%s)", body, ct)
+	}
+
+	// 旧版本的服务端只返回原始字节
+	old := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", rawContentType)
+		w.Write([]byte("Jack"))
+	}))
+	defer old.Close()
+	getter = newTestGetter(old.URL+defaultBasePath, http.DefaultClient)
+	out := &pb.GetResponse{}
+	if err := getter.GetProto(context.Background(), &pb.GetRequest{Group: "raw", Key: []byte("Jack")}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != "Jack" || out.GetExpire() != 0 {
+		t.Fatalf("response from raw peer = %v", out)
+	}
+}
+
+// roundTripCounter 统计经过它的请求数
+type roundTripCounter struct {
+	n int
+}
+
+func (c *roundTripCounter) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.n++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// 测试自定义的BasePath和Transport是否生效
+func TestNewHTTPPoolOpts(t *testing.T) {
+	NewGroup("opts", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	transport := &roundTripCounter{}
+	opts := &HTTPPoolOptions{BasePath: "/cache/", Replicas: 3, Transport: transport, Timeout: time.Second}
+
+	mux := http.NewServeMux()
+	mux.Handle("/cache/", NewHTTPPoolOpts("server", opts))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	pool := NewHTTPPoolOpts("client", opts)
+	pool.Set(srv.URL)
+	peer, ok := pool.PickPeer("Tom")
+	if !ok {
+		t.Fatal("Tom should be owned by the server")
+	}
+	if v, err := peer.Get("opts", "Tom"); err != nil || string(v) != "Tom" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if transport.n != 1 {
+		t.Fatalf("transport used %d times, expect 1", transport.n)
+	}
+}
+
+// FuzzKeyRoundTrip 测试任意字节组成的key经过httpGetter编码、http.ServeMux路由和ServeHTTP解码之后保持不变
+func FuzzKeyRoundTrip(f *testing.F) {
+	for _, key := range []string{"Tom", "a b", "a+b", "a/b", "/", "%", "%2F", "a%20b", ".", "..", "../x", "?x=1#y", "中文", "\xff\x00"} {
+		f.Add(key)
+	}
+	group := "fuzz/group %"
+	NewGroup(group, 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	mux := http.NewServeMux()
+	mux.Handle(defaultBasePath, NewHTTPPool("self"))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	getter := newTestGetter(srv.URL+defaultBasePath, srv.Client())
+
+	f.Fuzz(func(t *testing.T, key string) {
+		if key == "" {
+			t.Skip("key is required")
+		}
+		v, err := getter.Get(group, key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		if string(v) != key {
+			t.Fatalf("Get(%q) = %q", key, v)
+		}
+		if err := getter.Remove(context.Background(), group, key); err != nil {
+			t.Fatalf("Remove(%q): %v", key, err)
+		}
+	})
+}
+
+// 测试增删节点时已有节点的httpGetter被复用
+func TestHTTPPoolAddRemovePeers(t *testing.T) {
+	pool := NewHTTPPool("http://a")
+	pool.AddPeers("http://a", "http://b")
+	b := pool.httpGetters["http://b"]
+
+	pool.AddPeers("http://c")
+	if pool.httpGetters["http://b"] != b {
+		t.Fatal("AddPeers should reuse the existing getter")
+	}
+	pool.RemovePeers("http://c")
+	if _, ok := pool.httpGetters["http://c"]; ok {
+		t.Fatal("http://c should be removed")
+	}
+	pool.Set("http://a", "http://b", "http://d")
+	if pool.httpGetters["http://b"] != b {
+		t.Fatal("Set should reuse the existing getter")
+	}
+	if nodes := pool.peers.Nodes(); !reflect.DeepEqual(nodes, []string{"http://a", "http://b", "http://d"}) {
+		t.Fatalf("ring nodes = %v", nodes)
+	}
+}
+
+func TestHTTPPoolSetWeighted(t *testing.T) {
+	pool := NewHTTPPoolOpts("http://a", &HTTPPoolOptions{Replicas: 10})
+	if err := pool.SetWeighted(map[string]int64{"http://a": 1 << 30, "http://b": 2 << 30, "http://c": 1 << 40}); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := pool.peers.Nodes(); !reflect.DeepEqual(nodes, []string{"http://a", "http://b", "http://c"}) {
+		t.Fatalf("ring nodes = %v", nodes)
+	}
+	if n := len(pool.peers.GetN("Tom", 3)); n != 3 {
+		t.Fatalf("GetN returned %d nodes, expect 3", n)
+	}
+	// c的权重是a的1024倍，按maxWeightRatio处理
+	for _, share := range pool.peers.(*consistenthash.Map).Distribution() {
+		if share.Node == "http://c" && share.Replicas != 10*maxWeightRatio {
+			t.Fatalf("http://c has %d virtual nodes, expect %d", share.Replicas, 10*maxWeightRatio)
+		}
+	}
+
+	if err := pool.SetWeighted(map[string]int64{"http://a": 1, "http://d": 0}); err == nil {
+		t.Fatal("SetWeighted accepted a zero weight")
+	}
+	if nodes := pool.peers.Nodes(); len(nodes) != 3 {
+		t.Fatalf("ring nodes = %v after a rejected SetWeighted", nodes)
+	}
+}
+
+// 熔断的节点较多时，PickPeers需要多取一些后继节点
+func TestHTTPPoolPickPeersSkip(t *testing.T) {
+	var peers []string
+	for i := 0; i < 10; i++ {
+		peers = append(peers, "http://peer"+strconv.Itoa(i))
+	}
+	for _, placement := range []consistenthash.Placement{consistenthash.Ring, consistenthash.Rendezvous} {
+		pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{Placement: placement})
+		pool.Set(peers...)
+		order := pool.peers.GetN("Tom", len(peers))
+		for _, peer := range order[:5] {
+			pool.httpGetters[peer].breaker.failure()
+			pool.httpGetters[peer].breaker.failure()
+			pool.httpGetters[peer].breaker.failure()
+		}
+		var got []string
+		for _, peer := range pool.PickPeers("Tom", 2) {
+			got = append(got, peer.(*httpGetter).peer)
+		}
+		if !reflect.DeepEqual(got, order[5:7]) {
+			t.Fatalf("%s: PickPeers(Tom, 2) = %v, expect %v", placement, got, order[5:7])
+		}
+	}
+}
+
+// 有界负载模式下，所属节点正在处理的请求过多时，key交给哈希环上的下一个节点，请求结束后恢复
+func TestHTTPPoolBoundedLoad(t *testing.T) {
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{BoundedLoadFactor: 0.1})
+	pool.Set("http://b", "http://c")
+
+	owner, ok := pool.PickPeer("Tom")
+	if !ok {
+		t.Fatal("PickPeer(Tom) picked no peer")
+	}
+	getter := owner.(*httpGetter)
+	done1, done2 := getter.begin(), getter.begin()
+	other, ok := pool.PickPeer("Tom")
+	if !ok || other == owner {
+		t.Fatalf("PickPeer(Tom) = %v while %s is overloaded, expect the other peer", other, getter.peer)
+	}
+
+	done1()
+	done2()
+	if peer, _ := pool.PickPeer("Tom"); peer != owner {
+		t.Fatalf("PickPeer(Tom) = %v after requests completed, expect %s", peer, getter.peer)
+	}
+	if loads := pool.peers.(*consistenthash.Map).Loads(); loads[getter.peer] != 0 {
+		t.Fatalf("load of %s = %d after requests completed", getter.peer, loads[getter.peer])
+	}
+}
+
+// 本节点也在环上时，远程节点的负载上限只按远程节点计算，负载均衡时key不应该被挤到本地加载
+func TestHTTPPoolBoundedLoadWithSelf(t *testing.T) {
+	pool := NewHTTPPoolOpts("http://a", &HTTPPoolOptions{BoundedLoadFactor: 0.25})
+	pool.Set("http://a", "http://b", "http://c")
+
+	var (
+		mu             sync.Mutex
+		wg             sync.WaitGroup
+		dones          []func()
+		remote, locals int
+	)
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			peer, ok := pool.PickPeer(key)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				dones = append(dones, peer.(*httpGetter).begin())
+			}
+			if pool.peers.Get(key) != "http://a" {
+				remote++
+				if !ok {
+					locals++
+				}
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	if locals*10 > remote {
+		t.Fatalf("%d of %d remote keys fell back to local loads", locals, remote)
+	}
+	for _, done := range dones {
+		done()
+	}
+	if loads := pool.peers.(*consistenthash.Map).Loads(); loads["http://b"] != 0 || loads["http://c"] != 0 {
+		t.Fatalf("loads = %v after requests completed", loads)
+	}
+}
+
+func TestHTTPPoolPlacement(t *testing.T) {
+	for _, placement := range []consistenthash.Placement{consistenthash.Rendezvous, consistenthash.Jump, consistenthash.Maglev} {
+		pool := NewHTTPPoolOpts("http://a", &HTTPPoolOptions{Placement: placement})
+		if err := pool.SetWeighted(map[string]int64{"http://a": 1, "http://b": 2, "http://c": 3}); err != nil {
+			t.Fatal(err)
+		}
+		if nodes := pool.peers.Nodes(); !reflect.DeepEqual(nodes, []string{"http://a", "http://b", "http://c"}) {
+			t.Fatalf("%s: nodes = %v", placement, nodes)
+		}
+		for _, key := range []string{"Tom", "Jack", "Sam"} {
+			peer, ok := pool.PickPeer(key)
+			if owner := pool.peers.Get(key); ok != (owner != "http://a") || ok && peer.(*httpGetter).peer != owner {
+				t.Fatalf("%s: PickPeer(%s) = %v, %v, owner is %s", placement, key, peer, ok, owner)
+			}
+		}
+	}
+}
+
+func TestHTTPPoolHashTags(t *testing.T) {
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{HashTags: true})
+	pool.Set("http://a", "http://b", "http://c", "http://d")
+	for i := 0; i < 100; i++ {
+		tag := "{" + strconv.Itoa(i) + "}"
+		profile, _ := pool.PickPeer("user:" + tag + ":profile")
+		scores, _ := pool.PickPeer("user:" + tag + ":scores")
+		if profile != scores {
+			t.Fatalf("keys with hash tag %s picked different peers", tag)
+		}
+	}
+}
+
+// recordingPeer 启动一个记录收到的请求的测试服务器
+func recordingPeer(t *testing.T) (url string, requests func() []string) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reqs = append(reqs, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), reqs...)
+	}
+}
+
+// 所属节点熔断或者负载达到上限时，删除请求仍然发给它，而不是它的后继节点
+func TestGroupRemoveOwner(t *testing.T) {
+	a, aReqs := recordingPeer(t)
+	b, bReqs := recordingPeer(t)
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{FailureThreshold: 1, BoundedLoadFactor: 0.1})
+	pool.Set(a, b)
+	gee := NewGroup("removeowner", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	gee.RegisterPeers(pool)
+
+	owner := pool.peers.Get("Tom")
+	ownerReqs, otherReqs := aReqs, bReqs
+	if owner == b {
+		ownerReqs, otherReqs = bReqs, aReqs
+	}
+	getter := pool.httpGetters[owner]
+
+	// 所属节点熔断
+	getter.breaker.failure()
+	if peer, _ := pool.PickPeer("Tom"); peer == getter {
+		t.Fatal("PickPeer should skip the open-circuit owner")
+	}
+	if err := gee.Remove(context.Background(), "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	getter.breaker.success()
+
+	// 所属节点负载达到上限
+	done := getter.begin()
+	done2 := getter.begin()
+	if peer, _ := pool.PickPeer("Tom"); peer == getter {
+		t.Fatal("PickPeer should skip the overloaded owner")
+	}
+	if err := gee.Remove(context.Background(), "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	done()
+	done2()
+
+	want := []string{"DELETE /_geecache/removeowner/Tom", "DELETE /_geecache/removeowner/Tom"}
+	if got := ownerReqs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("owner %s got %v, expect %v", owner, got, want)
+	}
+	// 其余节点也会收到删除请求，用来删除hotCache中的副本
+	if got := otherReqs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("the other peer got %v, expect %v", got, want)
+	}
+}
+
+// 其余节点的hotCache中可能保存着从所属节点获取的副本，Remove需要通知所有节点
+func TestGroupRemoveHotCopies(t *testing.T) {
+	var (
+		urls []string
+		reqs []func() []string
+	)
+	for i := 0; i < 3; i++ {
+		url, requests := recordingPeer(t)
+		urls = append(urls, url)
+		reqs = append(reqs, requests)
+	}
+	pool := NewHTTPPoolOpts("http://self", nil)
+	pool.Set(append(urls, "http://self")...)
+	gee := NewGroup("removehot", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	gee.RegisterPeers(pool)
+	gee.hotCache.add("Tom", ByteView{b: []byte("stale")})
+
+	if err := gee.Remove(context.Background(), "Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gee.hotCache.get("Tom"); ok {
+		t.Fatal("stale Tom is still in the local hotCache")
+	}
+	for i, requests := range reqs {
+		if got := requests(); !reflect.DeepEqual(got, []string{"DELETE /_geecache/removehot/Tom"}) {
+			t.Fatalf("peer %s got %v, expect one DELETE", urls[i], got)
+		}
+	}
+}
+
+// 所属节点熔断时本节点代替它从本地加载，值只放入hotCache，不放入mainCache
+func TestGroupFailoverHotCache(t *testing.T) {
+	owner, _ := recordingPeer(t)
+	pool := NewHTTPPoolOpts("http://self", &HTTPPoolOptions{FailureThreshold: 1})
+	pool.Set(owner)
+	gee := NewGroup("failoverhot", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	gee.RegisterPeers(pool)
+
+	pool.httpGetters[owner].breaker.failure()
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" {
+		t.Fatalf("Get(Tom) = %v, %v", view, err)
+	}
+	if _, ok := gee.mainCache.get("Tom"); ok {
+		t.Fatal("Tom is owned by another peer and should not be in mainCache")
+	}
+	if _, ok := gee.hotCache.get("Tom"); !ok {
+		t.Fatal("Tom should be in hotCache")
+	}
+}
@@ -0,0 +1,211 @@
+package lru
+
+import (
+	"container/list"
+	"time"
+)
+
+// ARCCache 实现了自适应替换缓存（Megiddo & Modha, "ARC: A Self-Tuning, Low Overhead Replacement Cache"）
+// t1保存只被访问过一次的条目，t2保存被访问过至少两次的条目；b1、b2是幽灵链表，只保存最近从t1、t2淘汰的key
+// 新增的key如果命中b1，说明t1太小，增大t1的目标大小p；命中b2则减小p。淘汰时根据p决定从t1还是t2淘汰
+// 一次性扫描大量key只会冲刷t1，t2中频繁访问的条目不受影响
+// 原论文按条目数计算容量，这里按size函数计算的字节数计算，p、各链表的大小都是字节数
+// ARCCache is an adaptive replacement cache
+type ARCCache[K comparable, V any] struct {
+	maxBytes  int64
+	p         int64 // t1的目标大小
+	t1, t2    arcList
+	b1, b2    arcList
+	cache     map[K]*list.Element // key -> element in one of the four lists
+	size      func(key K, value V) int64
+	OnEvicted func(key K, value V, reason EvictReason)
+}
+
+// arcList 是带有总大小的链表，front是最近访问的
+type arcList struct {
+	ll    list.List
+	bytes int64
+}
+
+type arcEntry[K comparable, V any] struct {
+	entry[K, V]
+	list  *arcList
+	ghost bool // 在b1或b2中，value已经被丢弃
+}
+
+// NewARC 创建一个ARCCache，参数的含义与NewCache相同
+// NewARC creates an ARCCache
+func NewARC[K comparable, V any](maxBytes int64, size func(K, V) int64, onEvicted func(K, V, EvictReason)) *ARCCache[K, V] {
+	return &ARCCache[K, V]{
+		maxBytes:  maxBytes,
+		cache:     make(map[K]*list.Element),
+		size:      entrySize(size),
+		OnEvicted: onEvicted,
+	}
+}
+
+// push 把条目放入l的队尾（front）
+func (c *ARCCache[K, V]) push(kv *arcEntry[K, V], l *arcList) {
+	kv.list = l
+	l.bytes += kv.size
+	c.cache[kv.key] = l.ll.PushFront(kv)
+}
+
+// unlink 把条目从所在的链表中移出
+func (c *ARCCache[K, V]) unlink(ele *list.Element) *arcEntry[K, V] {
+	kv := ele.Value.(*arcEntry[K, V])
+	kv.list.ll.Remove(ele)
+	kv.list.bytes -= kv.size
+	kv.list = nil
+	return kv
+}
+
+// Get 命中t1或t2时把条目移动到t2
+// Get look ups a key's value
+func (c *ARCCache[K, V]) Get(key K) (value V, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return value, false
+	}
+	kv := ele.Value.(*arcEntry[K, V])
+	if kv.ghost {
+		return value, false
+	}
+	if kv.expired(now()) {
+		c.removeElement(ele, EvictExpired)
+		return value, false
+	}
+	c.push(c.unlink(ele), &c.t2)
+	return kv.value, true
+}
+
+// Add adds a value to the cache or edit a value
+func (c *ARCCache[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 按照ARC的规则放入条目：已经在t1或t2中的条目和命中幽灵链表的key放入t2，其余放入t1
+// AddWithExpire adds a value that expires at the given deadline
+func (c *ARCCache[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	size := c.size(key, value)
+	ele, ok := c.cache[key]
+	if !ok {
+		c.push(&arcEntry[K, V]{entry: entry[K, V]{key, value, expire, size}}, &c.t1)
+		c.evict()
+		return
+	}
+
+	kv := ele.Value.(*arcEntry[K, V])
+	if kv.ghost {
+		// 幽灵命中，按照两个幽灵链表大小的比例调整p
+		if kv.list == &c.b1 {
+			c.p = min(c.maxBytes, c.p+max(c.b2.bytes/max(c.b1.bytes, 1), 1)*size)
+		} else {
+			c.p = max(0, c.p-max(c.b1.bytes/max(c.b2.bytes, 1), 1)*size)
+		}
+		kv.ghost = false
+	}
+	// 先按原来的大小从链表中移出，再按新的大小放入t2
+	c.unlink(ele)
+	kv.value, kv.expire, kv.size = value, expire, size
+	c.push(kv, &c.t2)
+	c.evict()
+}
+
+// evict 先清理t1和t2中最旧的几个过期条目，再淘汰条目直到t1和t2的总大小不超过maxBytes，并限制幽灵链表的大小
+func (c *ARCCache[K, V]) evict() {
+	c.sweep(sweepSamples)
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.t1.bytes+c.t2.bytes > c.maxBytes {
+		c.RemoveOldest()
+	}
+	for c.b1.bytes > 0 && c.t1.bytes+c.b1.bytes > c.maxBytes {
+		c.dropGhost(&c.b1)
+	}
+	for c.b2.bytes > 0 && c.t1.bytes+c.t2.bytes+c.b1.bytes+c.b2.bytes > 2*c.maxBytes {
+		c.dropGhost(&c.b2)
+	}
+}
+
+// sweep 分别检查t1和t2中最旧的最多n个条目，删除其中已过期的
+func (c *ARCCache[K, V]) sweep(n int) {
+	t := now()
+	for _, l := range []*arcList{&c.t1, &c.t2} {
+		for ele, i := l.ll.Back(), 0; ele != nil && i < n; i++ {
+			prev := ele.Prev()
+			if ele.Value.(*arcEntry[K, V]).expired(t) {
+				c.removeElement(ele, EvictExpired)
+			}
+			ele = prev
+		}
+	}
+}
+
+// dropGhost 删除幽灵链表l中最旧的key
+func (c *ARCCache[K, V]) dropGhost(l *arcList) {
+	delete(c.cache, c.unlink(l.ll.Back()).key)
+}
+
+// RemoveOldest 即ARC中的REPLACE：t1超过目标大小p时淘汰t1中最旧的条目，否则淘汰t2中最旧的条目，被淘汰的key进入对应的幽灵链表
+// RemoveOldest removes the oldest item of t1 or t2
+func (c *ARCCache[K, V]) RemoveOldest() {
+	from, ghost := &c.t2, &c.b2
+	if c.t1.ll.Len() > 0 && (c.t1.bytes > c.p || c.t2.ll.Len() == 0) {
+		from, ghost = &c.t1, &c.b1
+	}
+	ele := from.ll.Back()
+	if ele == nil {
+		return
+	}
+	kv := c.unlink(ele)
+	value := kv.value
+	c.push(kv, ghost)
+	kv.ghost = true
+	var zero V
+	kv.value = zero
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, value, EvictCapacity)
+	}
+}
+
+// Remove removes the provided key from the cache
+func (c *ARCCache[K, V]) Remove(key K) bool {
+	if ele, ok := c.cache[key]; ok && !ele.Value.(*arcEntry[K, V]).ghost {
+		c.removeElement(ele, EvictRemoved)
+		return true
+	}
+	return false
+}
+
+func (c *ARCCache[K, V]) removeElement(ele *list.Element, reason EvictReason) {
+	kv := c.unlink(ele)
+	delete(c.cache, kv.key)
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// Range 先遍历t2再遍历t1，每个链表中最近访问的在前，不包括幽灵链表中的key
+// Range calls f for each entry of t2 and then t1 until f returns false
+func (c *ARCCache[K, V]) Range(f func(key K, value V) bool) {
+	t := now()
+	for _, l := range []*arcList{&c.t2, &c.t1} {
+		for ele := l.ll.Front(); ele != nil; ele = ele.Next() {
+			if kv := ele.Value.(*arcEntry[K, V]); !kv.expired(t) && !f(kv.key, kv.value) {
+				return
+			}
+		}
+	}
+}
+
+// Len the number of cache entries, not counting ghost keys
+func (c *ARCCache[K, V]) Len() int {
+	return c.t1.ll.Len() + c.t2.ll.Len()
+}
+
+// Bytes returns the bytes used by keys and values, not counting ghost keys
+func (c *ARCCache[K, V]) Bytes() int64 {
+	return c.t1.bytes + c.t2.bytes
+}
@@ -0,0 +1,188 @@
+package lru
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ClockCache 使用CLOCK算法近似LRU：所有条目排成一个环，命中时只设置条目的访问位，不移动任何位置
+// 淘汰时指针沿着环前进，访问位为1的条目清零后跳过（给它第二次机会），遇到访问位为0的条目就淘汰它
+// 访问位是原子变量，所以GetShared可以在多个goroutine中同时调用，调用者只需要持有读锁，代价是淘汰顺序不再是严格的LRU
+// ClockCache is a CLOCK cache whose hits only set an atomic reference bit
+type ClockCache[K comparable, V any] struct {
+	maxBytes  int64
+	nowBytes  int64
+	slots     []*clockEntry[K, V] // 环，nil表示空位
+	free      []int               // 空位的下标
+	hand      int                 // 下一个检查的位置
+	sweepHand int                 // sweep下一个检查的位置
+	cache     map[K]*clockEntry[K, V]
+	size      func(key K, value V) int64
+	OnEvicted func(key K, value V, reason EvictReason)
+}
+
+type clockEntry[K comparable, V any] struct {
+	entry[K, V]
+	slot int
+	ref  atomic.Bool
+}
+
+// SharedGetter 由可以在读锁下并发查找的实现提供，GetShared不会修改缓存的结构，过期的条目视为不存在，留给之后的写操作清理
+// 调用者需要保证GetShared不与其他方法同时执行，例如用sync.RWMutex的读锁保护GetShared，写锁保护其他方法
+// SharedGetter is implemented by caches whose lookups may run concurrently
+type SharedGetter[K comparable, V any] interface {
+	GetShared(key K) (value V, ok bool)
+}
+
+// NewClock 创建一个ClockCache，参数的含义与NewCache相同
+// NewClock creates a ClockCache
+func NewClock[K comparable, V any](maxBytes int64, size func(K, V) int64, onEvicted func(K, V, EvictReason)) *ClockCache[K, V] {
+	return &ClockCache[K, V]{
+		maxBytes:  maxBytes,
+		cache:     make(map[K]*clockEntry[K, V]),
+		size:      entrySize(size),
+		OnEvicted: onEvicted,
+	}
+}
+
+// GetShared 查找key并设置访问位，可以与其他GetShared并发执行
+// GetShared look ups a key's value without modifying the cache
+func (c *ClockCache[K, V]) GetShared(key K) (value V, ok bool) {
+	kv, ok := c.cache[key]
+	if !ok || kv.expired(now()) {
+		return value, false
+	}
+	kv.ref.Store(true)
+	return kv.value, true
+}
+
+// Get 与GetShared相同，但会立即删除过期的条目
+// Get look ups a key's value
+func (c *ClockCache[K, V]) Get(key K) (value V, ok bool) {
+	kv, ok := c.cache[key]
+	if !ok {
+		return value, false
+	}
+	if kv.expired(now()) {
+		c.removeEntry(kv, EvictExpired)
+		return value, false
+	}
+	kv.ref.Store(true)
+	return kv.value, true
+}
+
+// Add adds a value to the cache or edit a value
+func (c *ClockCache[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 新增的条目访问位为0，修改已有的条目算作一次访问
+// AddWithExpire adds a value that expires at the given deadline
+func (c *ClockCache[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	size := c.size(key, value)
+	if kv, ok := c.cache[key]; ok {
+		c.nowBytes += size - kv.size
+		kv.value, kv.expire, kv.size = value, expire, size
+		kv.ref.Store(true)
+	} else {
+		kv := &clockEntry[K, V]{entry: entry[K, V]{key, value, expire, size}}
+		if n := len(c.free); n > 0 {
+			kv.slot = c.free[n-1]
+			c.free = c.free[:n-1]
+			c.slots[kv.slot] = kv
+		} else {
+			kv.slot = len(c.slots)
+			c.slots = append(c.slots, kv)
+		}
+		c.cache[key] = kv
+		c.nowBytes += size
+	}
+	c.sweep(sweepSamples)
+	for c.maxBytes != 0 && c.maxBytes < c.nowBytes {
+		c.RemoveOldest()
+	}
+}
+
+// sweep 从sweepHand开始检查环上的n个位置，删除其中已过期的条目
+// 环上的条目没有新旧顺序，sweepHand独立于hand转动，不限制大小时hand不会移动，也能逐渐检查到所有条目
+func (c *ClockCache[K, V]) sweep(n int) {
+	t := now()
+	for ; n > 0 && len(c.slots) > 0; n-- {
+		if c.sweepHand >= len(c.slots) {
+			c.sweepHand = 0
+		}
+		kv := c.slots[c.sweepHand]
+		c.sweepHand++
+		if kv != nil && kv.expired(t) {
+			c.removeEntry(kv, EvictExpired)
+		}
+	}
+}
+
+// RemoveOldest 移动指针直到找到一个访问位为0的条目并淘汰它，途中遇到的过期条目会被直接淘汰
+// 每个条目最多被跳过一次，所以最多转两圈
+// RemoveOldest removes an entry that has not been referenced since the hand last passed it
+func (c *ClockCache[K, V]) RemoveOldest() {
+	if len(c.cache) == 0 {
+		return
+	}
+	t := now()
+	for {
+		if c.hand >= len(c.slots) {
+			c.hand = 0
+		}
+		kv := c.slots[c.hand]
+		c.hand++
+		switch {
+		case kv == nil:
+		case kv.expired(t):
+			c.removeEntry(kv, EvictExpired)
+			return
+		case kv.ref.Load():
+			kv.ref.Store(false)
+		default:
+			c.removeEntry(kv, EvictCapacity)
+			return
+		}
+	}
+}
+
+// Remove removes the provided key from the cache
+func (c *ClockCache[K, V]) Remove(key K) bool {
+	if kv, ok := c.cache[key]; ok {
+		c.removeEntry(kv, EvictRemoved)
+		return true
+	}
+	return false
+}
+
+func (c *ClockCache[K, V]) removeEntry(kv *clockEntry[K, V], reason EvictReason) {
+	c.slots[kv.slot] = nil
+	c.free = append(c.free, kv.slot)
+	delete(c.cache, kv.key)
+	c.nowBytes -= kv.size
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// Range 按环上的位置遍历条目，不改变访问位
+// Range calls f for each entry in slot order until f returns false
+func (c *ClockCache[K, V]) Range(f func(key K, value V) bool) {
+	t := now()
+	for _, kv := range c.slots {
+		if kv != nil && !kv.expired(t) && !f(kv.key, kv.value) {
+			return
+		}
+	}
+}
+
+// Len the number of cache entries
+func (c *ClockCache[K, V]) Len() int {
+	return len(c.cache)
+}
+
+// Bytes returns the bytes used by keys and values
+func (c *ClockCache[K, V]) Bytes() int64 {
+	return c.nowBytes
+}
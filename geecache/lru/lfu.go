@@ -0,0 +1,174 @@
+package lru
+
+import (
+	"container/list"
+	"time"
+)
+
+// LFUCache 淘汰访问次数最少的条目，所有操作都是O(1)的（Shah et al., "An O(1) algorithm for implementing the LFU cache eviction scheme"）
+// freqs是按访问次数从小到大排列的桶链表，每个桶保存访问次数相同的条目，桶内按最近访问的顺序排列
+// 访问一个条目时，把它移动到下一个桶（访问次数+1）的队尾，没有这个桶则新建；淘汰时取第一个桶的队首
+// LFUCache is a least-frequently-used cache
+type LFUCache[K comparable, V any] struct {
+	maxBytes  int64
+	nowBytes  int64
+	freqs     *list.List          // of *freqBucket, ascending freq
+	cache     map[K]*list.Element // element in freqBucket.items
+	size      func(key K, value V) int64
+	OnEvicted func(key K, value V, reason EvictReason)
+}
+
+// freqBucket 保存访问次数为freq的所有条目，front是最近访问的
+type freqBucket struct {
+	freq  int
+	items *list.List // of *lfuEntry
+}
+
+type lfuEntry[K comparable, V any] struct {
+	entry[K, V]
+	bucket *list.Element // element in LFUCache.freqs
+}
+
+// NewLFU 创建一个LFUCache，参数的含义与NewCache相同
+// NewLFU creates an LFUCache
+func NewLFU[K comparable, V any](maxBytes int64, size func(K, V) int64, onEvicted func(K, V, EvictReason)) *LFUCache[K, V] {
+	return &LFUCache[K, V]{
+		maxBytes:  maxBytes,
+		freqs:     list.New(),
+		cache:     make(map[K]*list.Element),
+		size:      entrySize(size),
+		OnEvicted: onEvicted,
+	}
+}
+
+// Get 查找key，命中时访问次数加一
+// Get look ups a key's value
+func (c *LFUCache[K, V]) Get(key K) (value V, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return value, false
+	}
+	kv := ele.Value.(*lfuEntry[K, V])
+	if kv.expired(now()) {
+		c.removeElement(ele, EvictExpired)
+		return value, false
+	}
+	c.touch(ele)
+	return kv.value, true
+}
+
+// touch 把条目移动到访问次数+1的桶中
+func (c *LFUCache[K, V]) touch(ele *list.Element) {
+	kv := ele.Value.(*lfuEntry[K, V])
+	cur := kv.bucket
+	b := cur.Value.(*freqBucket)
+	next := cur.Next()
+	if next == nil || next.Value.(*freqBucket).freq != b.freq+1 {
+		next = c.freqs.InsertAfter(&freqBucket{freq: b.freq + 1, items: list.New()}, cur)
+	}
+	b.items.Remove(ele)
+	if b.items.Len() == 0 {
+		c.freqs.Remove(cur)
+	}
+	kv.bucket = next
+	c.cache[kv.key] = next.Value.(*freqBucket).items.PushFront(kv)
+}
+
+// Add adds a value to the cache or edit a value
+func (c *LFUCache[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 新增的条目访问次数为1，修改已有的条目也算作一次访问
+// AddWithExpire adds a value that expires at the given deadline
+func (c *LFUCache[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	size := c.size(key, value)
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*lfuEntry[K, V])
+		c.nowBytes += size - kv.size
+		kv.value, kv.expire, kv.size = value, expire, size
+		c.touch(ele)
+	} else {
+		front := c.freqs.Front()
+		if front == nil || front.Value.(*freqBucket).freq != 1 {
+			front = c.freqs.PushFront(&freqBucket{freq: 1, items: list.New()})
+		}
+		kv := &lfuEntry[K, V]{entry: entry[K, V]{key, value, expire, size}, bucket: front}
+		c.cache[key] = front.Value.(*freqBucket).items.PushFront(kv)
+		c.nowBytes += size
+	}
+	c.sweep(sweepSamples)
+	for c.maxBytes != 0 && c.maxBytes < c.nowBytes {
+		c.RemoveOldest()
+	}
+}
+
+// sweep 按淘汰的顺序检查最多n个条目，删除其中已过期的
+func (c *LFUCache[K, V]) sweep(n int) {
+	t := now()
+	for b := c.freqs.Front(); b != nil && n > 0; {
+		next := b.Next() // b中的条目都被删除时b会被移出freqs
+		for ele := b.Value.(*freqBucket).items.Back(); ele != nil && n > 0; n-- {
+			prev := ele.Prev()
+			if ele.Value.(*lfuEntry[K, V]).expired(t) {
+				c.removeElement(ele, EvictExpired)
+			}
+			ele = prev
+		}
+		b = next
+	}
+}
+
+// RemoveOldest 淘汰访问次数最少的条目中最久未被访问的一个
+// RemoveOldest removes the least frequently used item
+func (c *LFUCache[K, V]) RemoveOldest() {
+	if front := c.freqs.Front(); front != nil {
+		c.removeElement(front.Value.(*freqBucket).items.Back(), EvictCapacity)
+	}
+}
+
+// Remove removes the provided key from the cache
+func (c *LFUCache[K, V]) Remove(key K) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele, EvictRemoved)
+		return true
+	}
+	return false
+}
+
+func (c *LFUCache[K, V]) removeElement(ele *list.Element, reason EvictReason) {
+	kv := ele.Value.(*lfuEntry[K, V])
+	b := kv.bucket.Value.(*freqBucket)
+	b.items.Remove(ele)
+	if b.items.Len() == 0 {
+		c.freqs.Remove(kv.bucket)
+	}
+	delete(c.cache, kv.key)
+	c.nowBytes -= kv.size
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// Range 按访问次数从多到少的顺序遍历条目，访问次数相同时最近访问的在前
+// Range calls f for each entry from most to least frequently used until f returns false
+func (c *LFUCache[K, V]) Range(f func(key K, value V) bool) {
+	t := now()
+	for b := c.freqs.Back(); b != nil; b = b.Prev() {
+		for ele := b.Value.(*freqBucket).items.Front(); ele != nil; ele = ele.Next() {
+			if kv := ele.Value.(*lfuEntry[K, V]); !kv.expired(t) && !f(kv.key, kv.value) {
+				return
+			}
+		}
+	}
+}
+
+// Len the number of cache entries
+func (c *LFUCache[K, V]) Len() int {
+	return len(c.cache)
+}
+
+// Bytes returns the bytes used by keys and values
+func (c *LFUCache[K, V]) Bytes() int64 {
+	return c.nowBytes
+}
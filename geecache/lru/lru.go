@@ -1,22 +1,73 @@
 package lru
 
-import "container/list"
+import (
+	"container/list"
+	"time"
+)
 
-type Cache struct {
-	maxBytes int64                    // 允许使用的最大内存
-	nowBytes int64                    // 当前使用的内存
-	ll       *list.List               // Go 语言标准库实现的双向链表list.List
-	cache    map[string]*list.Element // 键是字符串，值是双向链表中对应节点的指针，list.Element是Go语言标准库实现的双向链表节点
+// sweepSamples 每次Add时顺带检查的最旧条目数量，用来分摊过期条目的清理开销
+// sweepSamples is how many of the oldest entries Add inspects for expiry
+const sweepSamples = 3
+
+// now 获取当前时间，测试时可以替换
+var now = time.Now
+
+// EvictReason 表示条目被移除的原因，回调函数OnEvicted可以据此区分容量淘汰和过期淘汰
+// EvictReason describes why an entry was removed from the cache
+type EvictReason int
+
+const (
+	// EvictCapacity 因为超出maxBytes而被RemoveOldest淘汰
+	EvictCapacity EvictReason = iota
+	// EvictExpired 因为过期而被移除
+	EvictExpired
+	// EvictRemoved 被调用者通过Remove主动删除
+	EvictRemoved
+)
+
+// String returns a readable name of the reason
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictRemoved:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// TypedCache 是一个泛型的LRU缓存，K是键的类型，V是值的类型，每个条目占用的内存由size函数计算
+// TypedCache is an LRU cache. It is not safe for concurrent access.
+type TypedCache[K comparable, V any] struct {
+	maxBytes int64                      // 允许使用的最大内存
+	nowBytes int64                      // 当前使用的内存
+	ll       *list.List                 // Go 语言标准库实现的双向链表list.List
+	cache    map[K]*list.Element        // 键是K类型，值是双向链表中对应节点的指针，list.Element是Go语言标准库实现的双向链表节点
+	size     func(key K, value V) int64 // 计算一个条目占用的内存
 	// optional and executed when an entry is purged 可选，并在清除条目时执行下面这个方法(回调函数)
-	OnEvicted func(Key string, value Value) // 某条记录被移除时的回调函数，可以为 nil，即可以没有
+	OnEvicted func(key K, value V, reason EvictReason) // 某条记录被移除时的回调函数，可以为 nil，即可以没有
 }
 
 // 键值对 entry 是双向链表节点的数据类型，即Element中的Value存放的东西，在链表中仍保存每个值对应的 key 的好处在于，淘汰队首节点时，需要用 key 从字典中删除对应的映射。
-type entry struct {
-	key   string
-	value Value
+// expire是过期时间，零值表示永不过期；size是加入时由size函数计算的内存，删除时原样减去
+type entry[K comparable, V any] struct {
+	key    K
+	value  V
+	expire time.Time
+	size   int64
+}
+
+func (e *entry[K, V]) expired(t time.Time) bool {
+	return !e.expire.IsZero() && !t.Before(e.expire)
 }
 
+// Cache 是原来的字符串键缓存，即TypedCache[string, Value]，按len(key)+value.Len()计算内存
+// 定义为别名，使用*lru.Cache的代码不需要修改
+// Cache is a TypedCache with string keys and Value values
+type Cache = TypedCache[string, Value]
+
 // Value 为了通用性，我们允许值是实现了 Value 接口的任意类型，该接口只包含了一个方法 Len() int，用于返回值所占用的内存大小。
 // Value use Len to count how many bytes it takes
 type Value interface {
@@ -24,23 +75,39 @@ type Value interface {
 }
 
 // New is the Constructor of Cache
-func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
-	return &Cache{
+// 键是字符串，值实现了Value接口，每个条目占用len(key)+value.Len()字节
+func New(maxBytes int64, onEvicted func(string, Value, EvictReason)) *Cache {
+	return NewCache(maxBytes, func(key string, value Value) int64 {
+		return int64(len(key)) + int64(value.Len())
+	}, onEvicted)
+}
+
+// NewCache 创建一个泛型的TypedCache，size计算每个条目占用的内存，总和超过maxBytes时淘汰最久未使用的条目，maxBytes为0表示不限制
+// size为nil时每个条目计为1，此时maxBytes就是最多保存的条目数
+// NewCache creates a TypedCache whose entries are weighed by size
+func NewCache[K comparable, V any](maxBytes int64, size func(K, V) int64, onEvicted func(K, V, EvictReason)) *TypedCache[K, V] {
+	return &TypedCache[K, V]{
 		maxBytes:  maxBytes,
 		ll:        list.New(),
-		cache:     make(map[string]*list.Element),
+		cache:     make(map[K]*list.Element),
+		size:      entrySize(size),
 		OnEvicted: onEvicted,
 	}
 }
 
 // Get 查找功能
 // 查找主要有2个步骤，第一步是从字典中找到对应的双向链表的节点，第二步，将该节点移动到队尾
+// 如果节点已经过期，则惰性删除该节点并当作未命中处理
 // Get look ups a key's value
-func (c *Cache) Get(key string) (value Value, ok bool) {
+func (c *TypedCache[K, V]) Get(key K) (value V, ok bool) {
 	// 如果键对应的链表节点存在，则将对应节点移动到队尾，并返回查找到的值
 	if ele, ok := c.cache[key]; ok { // 从缓存map拿到的ele是双向链表的一个节点的指针*list.Element
-		c.ll.MoveToFront(ele)    // 将链表中的节点ele移动到队尾（双向链表作为队列，队首队尾是相对的，在这里约定front为队尾）
-		kv := ele.Value.(*entry) // 类型转换的第二种，断言 x.( T )，第二个返回值是bool
+		kv := ele.Value.(*entry[K, V]) // 类型转换的第二种，断言 x.( T )，第二个返回值是bool
+		if kv.expired(now()) {
+			c.removeElement(ele, EvictExpired)
+			return value, false
+		}
+		c.ll.MoveToFront(ele) // 将链表中的节点ele移动到队尾（双向链表作为队列，队首队尾是相对的，在这里约定front为队尾）
 		return kv.value, true
 	}
 	return // 这里是返回了value和ok的默认值
@@ -49,41 +116,196 @@ func (c *Cache) Get(key string) (value Value, ok bool) {
 // RemoveOldest 删除功能
 // 缓存淘汰，即移除最近最少访问的节点（队首）
 // RemoveOldest removes the oldest item
-func (c *Cache) RemoveOldest() {
+func (c *TypedCache[K, V]) RemoveOldest() {
 	ele := c.ll.Back() // 拿到队首节点的指针
 	if ele != nil {
-		c.ll.Remove(ele)                                         // 将该节点从双向链表中删除
-		kv := ele.Value.(*entry)                                 // 获取该节点Value存放的值
-		delete(c.cache, kv.key)                                  // 从字典（map）c.cache删除该节点的映射关系
-		c.nowBytes -= int64(len(kv.key)) + int64(kv.value.Len()) // 更新当前所用的内存c.nowBytes
-		if c.OnEvicted != nil {                                  // 如果回调函数OnEvicted存在的话，就调用回调函数
-			c.OnEvicted(kv.key, kv.value)
+		c.removeElement(ele, EvictCapacity)
+	}
+}
+
+// oldest 返回下一个会被RemoveOldest淘汰的条目，不改变它的位置
+func (c *TypedCache[K, V]) oldest() (kv *entry[K, V], ok bool) {
+	if ele := c.ll.Back(); ele != nil {
+		return ele.Value.(*entry[K, V]), true
+	}
+	return nil, false
+}
+
+// popOldest 删除并返回最旧的条目，不调用OnEvicted，由调用者决定条目的去向
+func (c *TypedCache[K, V]) popOldest() (kv *entry[K, V], ok bool) {
+	if ele := c.ll.Back(); ele != nil {
+		return c.unlink(ele), true
+	}
+	return nil, false
+}
+
+// unlink 删除并返回节点对应的条目，不调用OnEvicted
+func (c *TypedCache[K, V]) unlink(ele *list.Element) *entry[K, V] {
+	kv := ele.Value.(*entry[K, V])
+	c.ll.Remove(ele)
+	delete(c.cache, kv.key)
+	c.nowBytes -= kv.size
+	return kv
+}
+
+// Remove 主动删除key对应的节点，返回该key是否存在
+// Remove removes the provided key from the cache
+func (c *TypedCache[K, V]) Remove(key K) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele, EvictRemoved)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 遍历整个链表，删除所有已过期的节点，返回删除的个数
+// RemoveExpired removes all expired entries
+func (c *TypedCache[K, V]) RemoveExpired() int {
+	t := now()
+	n := 0
+	for ele := c.ll.Back(); ele != nil; {
+		prev := ele.Prev()
+		if ele.Value.(*entry[K, V]).expired(t) {
+			c.removeElement(ele, EvictExpired)
+			n++
+		}
+		ele = prev
+	}
+	return n
+}
+
+// sweep 从队首开始检查最多n个节点，删除其中已过期的，把过期清理的开销分摊到每次Add上
+func (c *TypedCache[K, V]) sweep(n int) {
+	t := now()
+	for ele := c.ll.Back(); ele != nil && n > 0; n-- {
+		prev := ele.Prev()
+		if ele.Value.(*entry[K, V]).expired(t) {
+			c.removeElement(ele, EvictExpired)
 		}
+		ele = prev
 	}
 }
 
-// Add 新增/修改
+func (c *TypedCache[K, V]) removeElement(ele *list.Element, reason EvictReason) {
+	c.ll.Remove(ele)               // 将该节点从双向链表中删除
+	kv := ele.Value.(*entry[K, V]) // 获取该节点Value存放的值
+	delete(c.cache, kv.key)        // 从字典（map）c.cache删除该节点的映射关系
+	c.nowBytes -= kv.size          // 更新当前所用的内存c.nowBytes
+	if c.OnEvicted != nil {        // 如果回调函数OnEvicted存在的话，就调用回调函数
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// Add 新增/修改，新增的节点永不过期
 // Add adds a value to the cache or edit a value
-func (c *Cache) Add(key string, value Value) {
+func (c *TypedCache[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 新增/修改，expire为过期时间，零值表示永不过期
+// AddWithExpire adds a value that expires at the given deadline
+func (c *TypedCache[K, V]) AddWithExpire(key K, value V, expire time.Time) {
 	if ele, ok := c.cache[key]; ok {
 		// 如果键存在，则更新对应节点的值，并将该节点移动到队尾
 		c.ll.MoveToFront(ele) // 将该节点移动到链表队尾
-		kv := ele.Value.(*entry)
-		c.nowBytes += int64(value.Len()) - int64(kv.value.Len()) // 更新c.nowBytes
-		kv.value = value                                         // 更新值
+		kv := ele.Value.(*entry[K, V])
+		size := c.size(key, value)
+		c.nowBytes += size - kv.size // 更新c.nowBytes
+		kv.value = value             // 更新值
+		kv.expire = expire
+		kv.size = size
 	} else {
 		// 不存在则是新增场景
-		ele := c.ll.PushFront(&entry{key, value})          // 队尾新增节点&entry{key,value}
-		c.cache[key] = ele                                 // 在字典中添加key和节点的映射关系
-		c.nowBytes += int64(len(key)) + int64(value.Len()) // 更新c.nowBytes
+		size := c.size(key, value)
+		ele := c.ll.PushFront(&entry[K, V]{key, value, expire, size}) // 队尾新增节点&entry{key,value,expire,size}
+		c.cache[key] = ele                                            // 在字典中添加key和节点的映射关系
+		c.nowBytes += size                                            // 更新c.nowBytes
 	}
+	// 顺带清理队首附近已经过期的节点
+	c.sweep(sweepSamples)
 	// 判断更新后的c.nowBytes是否比c.maxBytes大，如果超出就不断淘汰掉一些节点
 	for c.maxBytes != 0 && c.maxBytes < c.nowBytes {
 		c.RemoveOldest()
 	}
 }
 
-// Length Len the number of cache entries
-func (c *Cache) Length() int {
+// Bytes returns the bytes used by keys and values
+func (c *TypedCache[K, V]) Bytes() int64 {
+	return c.nowBytes
+}
+
+// Len the number of cache entries
+func (c *TypedCache[K, V]) Len() int {
 	return c.ll.Len()
 }
+
+// Length 与Len相同
+// Length Len the number of cache entries
+func (c *TypedCache[K, V]) Length() int {
+	return c.Len()
+}
+
+// MaxBytes returns the byte budget of the cache, 0 means unlimited
+func (c *TypedCache[K, V]) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// Peek 与Get相同，但不改变节点的位置，过期的节点视为不存在，但不会被删除
+// Peek look ups a key's value without updating its recency
+func (c *TypedCache[K, V]) Peek(key K) (value V, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		if kv := ele.Value.(*entry[K, V]); !kv.expired(now()) {
+			return kv.value, true
+		}
+	}
+	return value, false
+}
+
+// Contains 返回key是否在缓存中且没有过期，不改变节点的位置
+// Contains reports whether key is in the cache
+func (c *TypedCache[K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Keys 按最近访问的顺序返回所有没有过期的key，第一个是最近访问的
+// Keys returns the keys from most to least recently used
+func (c *TypedCache[K, V]) Keys() []K {
+	keys := make([]K, 0, c.ll.Len())
+	c.Range(func(key K, value V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Range 按最近访问的顺序对每个没有过期的条目调用f，f返回false时停止，f中不能修改缓存
+// Range calls f for each entry from most to least recently used until f returns false
+func (c *TypedCache[K, V]) Range(f func(key K, value V) bool) {
+	c.each(f)
+}
+
+// each 与Range相同，返回是否遍历完了所有条目
+func (c *TypedCache[K, V]) each(f func(key K, value V) bool) bool {
+	t := now()
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		if kv := ele.Value.(*entry[K, V]); !kv.expired(t) && !f(kv.key, kv.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Clear 删除所有条目，notify为true时对每个条目调用OnEvicted，原因为EvictRemoved
+// Clear removes all entries, optionally firing OnEvicted
+func (c *TypedCache[K, V]) Clear(notify bool) {
+	if notify && c.OnEvicted != nil {
+		for c.ll.Len() > 0 {
+			c.removeElement(c.ll.Back(), EvictRemoved)
+		}
+		return
+	}
+	c.ll.Init()
+	clear(c.cache)
+	c.nowBytes = 0
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type String string
@@ -44,7 +45,7 @@ func TestCache_RemoveOldest(t *testing.T) {
 
 func TestOnEvicted(t *testing.T) {
 	keys := make([]string, 0)
-	callback := func(key string, value Value) {
+	callback := func(key string, value Value, reason EvictReason) {
 		keys = append(keys, key)
 	}
 
@@ -61,3 +62,152 @@ func TestOnEvicted(t *testing.T) {
 		t.Fatalf("Call OnEvicted failed,expect keys equals to %s", expect)
 	}
 }
+
+// 测试过期的节点在Get时被惰性删除，并且回调函数收到的原因是EvictExpired
+func TestCache_Expire(t *testing.T) {
+	base := time.Now()
+	now = func() time.Time { return base }
+	defer func() { now = time.Now }()
+
+	reasons := make(map[string]EvictReason)
+	lru := New(int64(0), func(key string, value Value, reason EvictReason) {
+		reasons[key] = reason
+	})
+	lru.AddWithExpire("key1", String("1234"), base.Add(time.Second))
+	lru.Add("key2", String("5678"))
+	if _, ok := lru.Get("key1"); !ok {
+		t.Fatal("cache hit key1 before expire failed")
+	}
+
+	now = func() time.Time { return base.Add(2 * time.Second) }
+	if _, ok := lru.Get("key1"); ok || lru.Length() != 1 {
+		t.Fatal("expired key1 should be removed")
+	}
+	if _, ok := lru.Get("key2"); !ok {
+		t.Fatal("key2 without deadline should never expire")
+	}
+	if reasons["key1"] != EvictExpired {
+		t.Fatalf("evict reason of key1 is %s, expect %s", reasons["key1"], EvictExpired)
+	}
+}
+
+// 测试RemoveExpired能一次性清理所有过期节点
+func TestCache_RemoveExpired(t *testing.T) {
+	base := time.Now()
+	now = func() time.Time { return base }
+	defer func() { now = time.Now }()
+
+	lru := New(int64(0), nil)
+	lru.AddWithExpire("k1", String("v1"), base.Add(time.Second))
+	lru.AddWithExpire("k2", String("v2"), base.Add(time.Minute))
+	lru.AddWithExpire("k3", String("v3"), base.Add(time.Second))
+
+	now = func() time.Time { return base.Add(2 * time.Second) }
+	if n := lru.RemoveExpired(); n != 2 || lru.Length() != 1 {
+		t.Fatalf("RemoveExpired removed %d entries, expect 2", n)
+	}
+}
+
+func TestCache_Remove(t *testing.T) {
+	var reason EvictReason
+	lru := New(int64(0), func(key string, value Value, r EvictReason) {
+		reason = r
+	})
+	lru.Add("key1", String("1234"))
+	if !lru.Remove("key1") || reason != EvictRemoved {
+		t.Fatal("remove key1 failed")
+	}
+	if _, ok := lru.Get("key1"); ok || lru.Remove("key1") {
+		t.Fatal("key1 should be removed")
+	}
+}
+
+// 泛型的TypedCache不需要值实现Value接口，也不需要类型断言
+func TestNewCache(t *testing.T) {
+	var evicted []int
+	c := NewCache(2, nil, func(key int, value []string, reason EvictReason) {
+		evicted = append(evicted, key)
+	})
+	c.Add(1, []string{"a"})
+	c.Add(2, []string{"b", "c"})
+	c.Add(3, nil)
+	if v, ok := c.Get(2); !ok || len(v) != 2 {
+		t.Fatalf("Get(2) = %v, %v", v, ok)
+	}
+	if !reflect.DeepEqual(evicted, []int{1}) || c.Length() != 2 || c.Bytes() != 2 {
+		t.Fatalf("evicted %v, %d entries of %d bytes; expect [1], 2 entries of 2 bytes", evicted, c.Length(), c.Bytes())
+	}
+
+	// size按值的长度计算
+	sized := NewCache(10, func(key string, value []byte) int64 {
+		return int64(len(value))
+	}, nil)
+	sized.Add("a", make([]byte, 6))
+	sized.Add("b", make([]byte, 6))
+	if _, ok := sized.Get("a"); ok || sized.Bytes() != 6 {
+		t.Fatalf("a should be evicted, %d bytes used", sized.Bytes())
+	}
+	sized.Add("b", make([]byte, 2))
+	if sized.Bytes() != 2 {
+		t.Fatalf("%d bytes used after updating b, expect 2", sized.Bytes())
+	}
+}
+
+// Peek和Contains不改变淘汰顺序
+func TestCache_Peek(t *testing.T) {
+	lru := NewCache[string, int](2, nil, nil)
+	lru.Add("k1", 1)
+	lru.Add("k2", 2)
+	if v, ok := lru.Peek("k1"); !ok || v != 1 || !lru.Contains("k1") || lru.Contains("k3") {
+		t.Fatalf("Peek(k1) = %v, %v", v, ok)
+	}
+	lru.Add("k3", 3)
+	if lru.Contains("k1") {
+		t.Fatal("k1 should be evicted, Peek must not bump its recency")
+	}
+	if lru.MaxBytes() != 2 {
+		t.Fatalf("MaxBytes() = %d, expect 2", lru.MaxBytes())
+	}
+}
+
+func TestCache_Keys(t *testing.T) {
+	lru := NewCache[string, int](0, nil, nil)
+	lru.Add("k1", 1)
+	lru.Add("k2", 2)
+	lru.Add("k3", 3)
+	lru.Get("k1")
+	if keys := lru.Keys(); !reflect.DeepEqual(keys, []string{"k1", "k3", "k2"}) {
+		t.Fatalf("Keys() = %v, expect [k1 k3 k2]", keys)
+	}
+
+	defer func(orig func() time.Time) { now = orig }(now)
+	base := time.Unix(1000, 0)
+	now = func() time.Time { return base }
+	lru.AddWithExpire("k4", 4, base)
+	var sum int
+	lru.Range(func(key string, value int) bool {
+		sum += value
+		return key != "k3"
+	})
+	if sum != 4 {
+		t.Fatalf("Range visited values adding up to %d, expect 1+3 without the expired k4", sum)
+	}
+}
+
+func TestCache_Clear(t *testing.T) {
+	var removed []string
+	lru := New(0, func(key string, value Value, reason EvictReason) {
+		removed = append(removed, key+":"+reason.String())
+	})
+	lru.Add("k1", String("1"))
+	lru.Add("k2", String("2"))
+	lru.Clear(true)
+	if !reflect.DeepEqual(removed, []string{"k1:removed", "k2:removed"}) || lru.Len() != 0 || lru.Bytes() != 0 {
+		t.Fatalf("removed %v, %d entries of %d bytes left", removed, lru.Len(), lru.Bytes())
+	}
+	lru.Add("k3", String("3"))
+	lru.Clear(false)
+	if len(removed) != 2 || lru.Len() != 0 || lru.Bytes() != 0 || len(lru.Keys()) != 0 {
+		t.Fatalf("Clear(false) should not fire OnEvicted, removed %v", removed)
+	}
+}
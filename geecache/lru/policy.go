@@ -0,0 +1,94 @@
+package lru
+
+import "time"
+
+// Policy 是缓存淘汰策略的抽象，Cache（LRU）、LFUCache、ARCCache、TinyLFUCache和ClockCache都实现了这个接口
+// 所有实现都按size函数计算条目占用的内存，超过maxBytes时淘汰条目，淘汰的条目通过OnEvicted通知调用者
+// 实现都不是并发安全的，由调用者加锁
+// Policy is a size-bounded cache with some eviction policy
+type Policy[K comparable, V any] interface {
+	// Add 新增或修改一个永不过期的条目
+	Add(key K, value V)
+	// AddWithExpire 新增或修改一个在expire时过期的条目，零值表示永不过期
+	AddWithExpire(key K, value V, expire time.Time)
+	// Get 查找key，过期的条目视为不存在
+	Get(key K) (value V, ok bool)
+	// Remove 删除key，返回key是否存在
+	Remove(key K) bool
+	// RemoveOldest 按照淘汰策略淘汰一个条目
+	RemoveOldest()
+	// Len 返回条目数
+	Len() int
+	// Bytes 返回所有条目占用的内存
+	Bytes() int64
+	// Range 对每个没有过期的条目调用f，f返回false时停止，f中不能修改缓存，遍历的顺序由各个实现决定
+	Range(f func(key K, value V) bool)
+}
+
+// PolicyKind 表示淘汰策略的种类
+// PolicyKind selects a Policy implementation
+type PolicyKind int
+
+const (
+	// LRU 淘汰最久未被访问的条目，即TypedCache，是默认的策略
+	LRU PolicyKind = iota
+	// LFU 淘汰访问次数最少的条目，访问次数相同时淘汰最久未被访问的
+	LFU
+	// ARC 自适应替换缓存，在最近访问和频繁访问之间自动调整，能抵抗扫描式的访问
+	ARC
+	// TinyLFU 即W-TinyLFU，LRU前面加上基于访问频率的准入过滤，只出现一次的key不会挤掉热点条目
+	TinyLFU
+	// Clock 用CLOCK算法近似LRU，命中时只设置原子的访问位，查找可以在读锁下并发执行，见ClockCache
+	Clock
+)
+
+// String returns the name of the policy
+func (k PolicyKind) String() string {
+	switch k {
+	case LRU:
+		return "lru"
+	case LFU:
+		return "lfu"
+	case ARC:
+		return "arc"
+	case TinyLFU:
+		return "tinylfu"
+	case Clock:
+		return "clock"
+	}
+	return "unknown"
+}
+
+// NewPolicy 按kind创建一个淘汰策略，参数的含义与NewCache相同
+// NewPolicy creates a Policy of the given kind
+func NewPolicy[K comparable, V any](kind PolicyKind, maxBytes int64, size func(K, V) int64, onEvicted func(K, V, EvictReason)) Policy[K, V] {
+	switch kind {
+	case LFU:
+		return NewLFU(maxBytes, size, onEvicted)
+	case ARC:
+		return NewARC(maxBytes, size, onEvicted)
+	case TinyLFU:
+		return NewTinyLFU(maxBytes, size, onEvicted)
+	case Clock:
+		return NewClock(maxBytes, size, onEvicted)
+	}
+	return NewCache(maxBytes, size, onEvicted)
+}
+
+// entrySize 返回size函数，size为nil时每个条目计为1
+func entrySize[K comparable, V any](size func(K, V) int64) func(K, V) int64 {
+	if size == nil {
+		return func(K, V) int64 { return 1 }
+	}
+	return size
+}
+
+var (
+	_ Policy[string, Value] = (*Cache)(nil)
+	_ Policy[string, Value] = (*LFUCache[string, Value])(nil)
+	_ Policy[string, Value] = (*ARCCache[string, Value])(nil)
+	_ Policy[string, Value] = (*TinyLFUCache[string, Value])(nil)
+	_ Policy[string, Value] = (*ClockCache[string, Value])(nil)
+
+	_ SharedGetter[string, Value] = (*ClockCache[string, Value])(nil)
+)
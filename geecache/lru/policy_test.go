@@ -0,0 +1,336 @@
+package lru
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var kinds = []PolicyKind{LRU, LFU, ARC, TinyLFU, Clock}
+
+// 所有策略都满足Policy接口的基本约定
+func TestPolicy(t *testing.T) {
+	for _, kind := range kinds {
+		t.Run(kind.String(), func(t *testing.T) {
+			var evicted []string
+			c := NewPolicy(kind, 3, nil, func(key string, value int, reason EvictReason) {
+				evicted = append(evicted, key+":"+reason.String())
+			})
+			c.Add("a", 1)
+			c.Add("b", 2)
+			c.Add("c", 3)
+			if v, ok := c.Get("a"); !ok || v != 1 {
+				t.Fatalf("Get(a) = %v, %v", v, ok)
+			}
+			c.Add("d", 4)
+			if c.Len() != 3 || c.Bytes() != 3 || len(evicted) != 1 {
+				t.Fatalf("%d entries of %d bytes, evicted %v", c.Len(), c.Bytes(), evicted)
+			}
+			if _, ok := c.Get("a"); !ok {
+				t.Fatal("a was accessed and should not be evicted")
+			}
+			if !c.Remove("d") || c.Remove("d") || c.Len() != 2 {
+				t.Fatal("Remove(d) failed")
+			}
+			c.Add("e", 5)
+			c.RemoveOldest()
+			if c.Len() != 2 {
+				t.Fatalf("%d entries after RemoveOldest, expect 2", c.Len())
+			}
+			n := 0
+			c.Range(func(key string, value int) bool {
+				n++
+				return true
+			})
+			if n != 2 {
+				t.Fatalf("Range visited %d entries, expect 2", n)
+			}
+
+			defer func(orig func() time.Time) { now = orig }(now)
+			base := time.Unix(1000, 0)
+			now = func() time.Time { return base }
+			c.AddWithExpire("f", 6, base.Add(time.Second))
+			now = func() time.Time { return base.Add(time.Second) }
+			if _, ok := c.Get("f"); ok {
+				t.Fatal("f should be expired")
+			}
+			if last := evicted[len(evicted)-1]; last != "f:expired" {
+				t.Fatalf("last eviction is %s, expect f:expired", last)
+			}
+		})
+	}
+}
+
+// 所有策略在Add时都会顺带清理过期的条目，没有Get也不会一直占着内存
+func TestPolicySweep(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	base := time.Unix(1000, 0)
+	for _, kind := range kinds {
+		t.Run(kind.String(), func(t *testing.T) {
+			expired := 0
+			c := NewPolicy(kind, 1000, nil, func(key string, value int, reason EvictReason) {
+				if reason == EvictExpired {
+					expired++
+				}
+			})
+			now = func() time.Time { return base }
+			for i := 0; i < 3; i++ {
+				c.AddWithExpire("expire"+strconv.Itoa(i), i, base.Add(time.Second))
+			}
+			for i := 0; i < 10; i++ {
+				c.Add("a"+strconv.Itoa(i), i)
+			}
+			now = func() time.Time { return base.Add(time.Second) }
+			for i := 0; i < 20; i++ {
+				c.Add("b"+strconv.Itoa(i), i)
+			}
+			if expired != 3 || c.Len() != 30 {
+				t.Fatalf("%d entries, %d expired evictions, expect 30 and 3", c.Len(), expired)
+			}
+		})
+	}
+}
+
+func TestLFU(t *testing.T) {
+	var evicted []string
+	c := NewLFU(3, nil, func(key string, value int, reason EvictReason) {
+		evicted = append(evicted, key)
+	})
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	// c访问次数最少，先被淘汰；d访问次数为1，比b少，再被淘汰
+	c.Add("d", 4)
+	c.Add("e", 5)
+	if !reflect.DeepEqual(evicted, []string{"c", "d"}) {
+		t.Fatalf("evicted %v, expect [c d]", evicted)
+	}
+	if c.freqs.Len() != 3 {
+		t.Fatalf("%d frequency buckets, expect 3", c.freqs.Len())
+	}
+}
+
+// 访问过两次的key不会被一次性的扫描冲掉
+func TestARCScanResistance(t *testing.T) {
+	c := NewARC[string, int](10, nil, nil)
+	for i := 0; i < 5; i++ {
+		key := "hot" + strconv.Itoa(i)
+		c.Add(key, i)
+		c.Get(key)
+	}
+	for i := 0; i < 100; i++ {
+		c.Add("scan"+strconv.Itoa(i), i)
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := c.Get("hot" + strconv.Itoa(i)); !ok {
+			t.Fatalf("hot%d was evicted by the scan", i)
+		}
+	}
+	if c.Len() != 10 || c.t1.bytes+c.b1.bytes > 10 {
+		t.Fatalf("%d entries, t1+b1 = %d bytes", c.Len(), c.t1.bytes+c.b1.bytes)
+	}
+}
+
+// zipfTrace 生成n个服从Zipf分布的key，scanEvery大于0时每隔scanEvery个key插入一段scanLen个只出现一次的key
+func zipfTrace(n, scanEvery, scanLen int) []string {
+	r := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(r, 1.1, 1, 1<<20)
+	trace := make([]string, 0, n)
+	scanned := 0
+	for len(trace) < n {
+		if scanEvery > 0 && len(trace)%scanEvery == 0 {
+			for i := 0; i < scanLen; i++ {
+				trace = append(trace, "scan"+strconv.Itoa(scanned))
+				scanned++
+			}
+		}
+		trace = append(trace, strconv.FormatUint(zipf.Uint64(), 10))
+	}
+	return trace
+}
+
+// BenchmarkHitRatio 在Zipf分布的访问序列上比较各个策略的命中率，hit%是命中率
+// 未命中时模拟从数据源加载后放入缓存，每次迭代用新的缓存回放整个序列，命中率与b.N无关
+func BenchmarkHitRatio(b *testing.B) {
+	traces := map[string][]string{
+		"zipf":      zipfTrace(1<<18, 0, 0),
+		"zipf+scan": zipfTrace(1<<18, 5000, 2000),
+	}
+	for _, name := range []string{"zipf", "zipf+scan"} {
+		trace := traces[name]
+		for _, kind := range kinds {
+			b.Run(fmt.Sprintf("%s/%s", name, kind), func(b *testing.B) {
+				hits := 0
+				for i := 0; i < b.N; i++ {
+					c := NewPolicy[string, struct{}](kind, 1000, nil, nil)
+					for _, key := range trace {
+						if _, ok := c.Get(key); ok {
+							hits++
+						} else {
+							c.Add(key, struct{}{})
+						}
+					}
+				}
+				b.ReportMetric(float64(hits)/float64(b.N*len(trace))*100, "hit%")
+			})
+		}
+	}
+}
+
+// 热点条目不会被大量只出现一次的key挤出主缓存
+func TestTinyLFUAdmission(t *testing.T) {
+	var evicted int
+	c := NewTinyLFU(100, nil, func(key string, value int, reason EvictReason) {
+		evicted++
+	})
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 50; i++ {
+			key := "hot" + strconv.Itoa(i)
+			if _, ok := c.Get(key); !ok {
+				c.Add(key, i)
+			}
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		c.Add("once"+strconv.Itoa(i), i)
+	}
+	for i := 0; i < 50; i++ {
+		if _, ok := c.main.get("hot" + strconv.Itoa(i)); !ok {
+			t.Fatalf("hot%d was evicted by one-hit wonders", i)
+		}
+	}
+	if c.Len() != 100 || c.window.Len() != 1 || evicted != 1000-50 {
+		t.Fatalf("%d entries, %d in window, %d evicted", c.Len(), c.window.Len(), evicted)
+	}
+}
+
+// 比窗口还大的条目只有访问频率高于所有会因它被淘汰的条目时才进入主缓存，不影响受保护的段
+func TestTinyLFUSizeWeighted(t *testing.T) {
+	c := NewTinyLFU(1000, func(key string, value int) int64 {
+		return int64(value)
+	}, nil)
+	get := func(key string, size int) {
+		if _, ok := c.Get(key); !ok {
+			c.Add(key, size)
+		}
+	}
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 100; i++ {
+			get("hot"+strconv.Itoa(i), 5)
+		}
+	}
+	for i := 0; i < 96; i++ {
+		get("cold"+strconv.Itoa(i), 5)
+	}
+	protected := c.main.protected.Keys()
+	get("big", 50)
+	if c.main.probation.Contains("big") {
+		t.Fatalf("one-hit big entry was admitted, window is %d bytes", c.windowBytes)
+	}
+	// 试用段最旧的是几个访问了3次的hot条目，big至少要访问4次才能挤掉它们
+	for i := 0; i < 3; i++ {
+		get("big", 50)
+	}
+	if !c.main.probation.Contains("big") {
+		t.Fatal("frequently accessed big entry was rejected")
+	}
+	for _, key := range protected {
+		if !c.main.protected.Contains(key) {
+			t.Fatalf("protected %s was evicted by the big entry", key)
+		}
+	}
+	if c.Bytes() > c.maxBytes {
+		t.Fatalf("%d bytes used, max is %d", c.Bytes(), c.maxBytes)
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := newCountMinSketch(0)
+	for i := 0; i < 20; i++ {
+		s.increment(42)
+	}
+	if est := s.estimate(42); est != sketchMaxCnt {
+		t.Fatalf("estimate = %d, expect the counter to saturate at %d", est, sketchMaxCnt)
+	}
+	s.age()
+	if est := s.estimate(42); est != sketchMaxCnt/2 {
+		t.Fatalf("estimate = %d after aging, expect %d", est, sketchMaxCnt/2)
+	}
+
+	d := newDoorkeeper(1024)
+	if d.add(42) || !d.add(42) || !d.has(42) {
+		t.Fatal("doorkeeper should remember 42")
+	}
+	d.reset()
+	if d.has(42) {
+		t.Fatal("doorkeeper should be empty after reset")
+	}
+}
+
+// 被访问过的条目会得到第二次机会
+func TestClock(t *testing.T) {
+	var evicted []string
+	c := NewClock(3, nil, func(key string, value int, reason EvictReason) {
+		evicted = append(evicted, key)
+	})
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("c", 3)
+	c.GetShared("a")
+	c.Get("c")
+	c.Add("d", 4) // d放入新的位置，指针跳过a，淘汰b
+	c.Add("e", 5) // e放入b的空位，指针跳过c，淘汰没有被访问过的d
+	if !reflect.DeepEqual(evicted, []string{"b", "d"}) {
+		t.Fatalf("evicted %v, expect [b d]", evicted)
+	}
+	if len(c.slots) != 4 || !reflect.DeepEqual(c.free, []int{3}) {
+		t.Fatalf("%d slots, free %v; expect the freed slot to be reused", len(c.slots), c.free)
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a should survive with its second chance")
+	}
+}
+
+// BenchmarkGetParallel 比较并发查找时，Cache在互斥锁下Get与ClockCache在读锁下GetShared的吞吐量
+func BenchmarkGetParallel(b *testing.B) {
+	const keys = 1 << 14
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = strconv.Itoa(i)
+	}
+	b.Run("lru/mutex", func(b *testing.B) {
+		var mu sync.Mutex
+		c := NewCache[string, int](0, nil, nil)
+		for i, key := range names {
+			c.Add(key, i)
+		}
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				mu.Lock()
+				c.Get(names[(i*7919)%keys])
+				mu.Unlock()
+			}
+		})
+	})
+	b.Run("clock/rwmutex", func(b *testing.B) {
+		var mu sync.RWMutex
+		c := NewClock[string, int](0, nil, nil)
+		for i, key := range names {
+			c.Add(key, i)
+		}
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				mu.RLock()
+				c.GetShared(names[(i*7919)%keys])
+				mu.RUnlock()
+			}
+		})
+	})
+}
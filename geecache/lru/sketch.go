@@ -0,0 +1,110 @@
+package lru
+
+// countMinSketch 以很小的内存估计每个key最近被访问的次数（Count-Min Sketch）
+// 每个key在depth行中各对应一个4位的计数器（这里用uint8保存，上限为15），估计值取各行的最小值
+// 记录的次数达到sampleSize后，所有计数器减半（老化），使估计值反映的是最近的访问频率
+type countMinSketch struct {
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+const (
+	sketchDepth  = 4
+	sketchMaxCnt = 15
+	// minSketchWidth 每行计数器的最少个数
+	minSketchWidth = 1024
+)
+
+// newCountMinSketch 创建每行有width个计数器的sketch，width会被向上取整为2的幂
+func newCountMinSketch(width int) *countMinSketch {
+	w := minSketchWidth
+	for w < width {
+		w <<= 1
+	}
+	s := &countMinSketch{mask: uint64(w - 1), sampleSize: 10 * w}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+	return s
+}
+
+// index 使用双重哈希从h得到第i行的位置
+func (s *countMinSketch) index(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & s.mask
+}
+
+// increment 记录一次访问，返回是否触发了老化
+func (s *countMinSketch) increment(h uint64) (aged bool) {
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < sketchMaxCnt {
+			*c++
+		}
+	}
+	if s.additions++; s.additions >= s.sampleSize {
+		s.age()
+		return true
+	}
+	return false
+}
+
+// estimate 返回h对应的key的访问次数估计值
+func (s *countMinSketch) estimate(h uint64) int {
+	est := sketchMaxCnt
+	for i := range s.rows {
+		est = min(est, int(s.rows[i][s.index(h, i)]))
+	}
+	return est
+}
+
+// age 所有计数器减半
+func (s *countMinSketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// doorkeeper 是一个布隆过滤器，key第一次被访问时只记录在doorkeeper中，第二次访问才计入countMinSketch
+// 这样大量只出现一次的key不会占满sketch的计数器，sketch老化时doorkeeper一并清空
+type doorkeeper struct {
+	bits []uint64
+	mask uint64
+}
+
+// newDoorkeeper 创建有n位的过滤器，n会被向上取整为64的倍数且为2的幂
+func newDoorkeeper(n int) *doorkeeper {
+	w := 64
+	for w < n {
+		w <<= 1
+	}
+	return &doorkeeper{bits: make([]uint64, w/64), mask: uint64(w - 1)}
+}
+
+// add 记录h，返回h是否已经存在
+func (d *doorkeeper) add(h uint64) (present bool) {
+	present = true
+	for _, bit := range [2]uint64{h & d.mask, (h >> 32) & d.mask} {
+		if d.bits[bit/64]&(1<<(bit%64)) == 0 {
+			present = false
+			d.bits[bit/64] |= 1 << (bit % 64)
+		}
+	}
+	return present
+}
+
+func (d *doorkeeper) has(h uint64) bool {
+	for _, bit := range [2]uint64{h & d.mask, (h >> 32) & d.mask} {
+		if d.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *doorkeeper) reset() {
+	clear(d.bits)
+}
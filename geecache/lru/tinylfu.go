@@ -0,0 +1,280 @@
+package lru
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+const (
+	// windowRatio 窗口LRU占maxBytes的1/windowRatio
+	windowRatio = 100
+	// protectedRatio 主缓存中受保护的段所占的百分比
+	protectedRatio = 80
+)
+
+// TinyLFUCache 实现了W-TinyLFU（Einziger et al., "TinyLFU: A Highly Efficient Cache Admission Policy"）
+// 新的条目先进入一个很小的窗口LRU，从窗口淘汰的条目成为候选者，只有当它的访问频率估计值高于主缓存中将被淘汰的条目时才会进入主缓存，否则直接丢弃
+// 访问频率由带老化的countMinSketch估计，前面加一个doorkeeper过滤只出现一次的key
+// 主缓存是分段LRU（SLRU），在主缓存中再次被访问的条目进入受保护的段，不会被新准入的条目直接淘汰
+// 这样偶尔被访问一次的key（例如扫描或者从远程节点获取的冷数据）不会把主缓存中真正热的条目挤出去，窗口则让突发的新热点有机会积累频率
+// TinyLFUCache is a W-TinyLFU cache: a window LRU in front of a main LRU guarded by a frequency-based admission filter
+type TinyLFUCache[K comparable, V any] struct {
+	maxBytes    int64
+	windowBytes int64
+	mainBytes   int64
+	window      *TypedCache[K, V]
+	main        *slru[K, V]
+	sketch      *countMinSketch
+	door        *doorkeeper
+	seed        maphash.Seed
+	OnEvicted   func(key K, value V, reason EvictReason)
+}
+
+// NewTinyLFU 创建一个TinyLFUCache，参数的含义与NewCache相同，maxBytes为0时不限制内存，也不做准入过滤
+// NewTinyLFU creates a TinyLFUCache
+func NewTinyLFU[K comparable, V any](maxBytes int64, size func(K, V) int64, onEvicted func(K, V, EvictReason)) *TinyLFUCache[K, V] {
+	c := &TinyLFUCache[K, V]{
+		maxBytes:  maxBytes,
+		sketch:    newCountMinSketch(0),
+		door:      newDoorkeeper(8 * minSketchWidth),
+		seed:      maphash.MakeSeed(),
+		OnEvicted: onEvicted,
+	}
+	if maxBytes > 0 {
+		c.windowBytes = max(maxBytes/windowRatio, 1)
+		c.mainBytes = max(maxBytes-c.windowBytes, 1)
+	}
+	// 两个LRU都不限制大小，由c按windowBytes和mainBytes淘汰
+	evicted := func(key K, value V, reason EvictReason) {
+		if c.OnEvicted != nil {
+			c.OnEvicted(key, value, reason)
+		}
+	}
+	c.window = NewCache(0, size, evicted)
+	c.main = &slru[K, V]{
+		protectedBytes: c.mainBytes * protectedRatio / 100,
+		probation:      NewCache(0, size, evicted),
+		protected:      NewCache(0, size, evicted),
+	}
+	return c
+}
+
+func (c *TinyLFUCache[K, V]) hash(key K) uint64 {
+	return maphash.Comparable(c.seed, key)
+}
+
+// record 记录一次访问：第一次访问只记录在doorkeeper中，之后才计入sketch
+// 只有Get会记录访问，否则未命中后紧接着的Add会让只出现一次的key也拥有两次访问
+func (c *TinyLFUCache[K, V]) record(key K) {
+	h := c.hash(key)
+	if c.door.add(h) && c.sketch.increment(h) {
+		c.door.reset()
+	}
+}
+
+// frequency 返回key的访问频率估计值
+func (c *TinyLFUCache[K, V]) frequency(key K) int {
+	h := c.hash(key)
+	f := c.sketch.estimate(h)
+	if c.door.has(h) {
+		f++
+	}
+	return f
+}
+
+// Get look ups a key's value
+func (c *TinyLFUCache[K, V]) Get(key K) (value V, ok bool) {
+	c.record(key)
+	if value, ok = c.window.Get(key); ok {
+		return value, ok
+	}
+	return c.main.get(key)
+}
+
+// Add adds a value to the cache or edit a value
+func (c *TinyLFUCache[K, V]) Add(key K, value V) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 已经在主缓存中的key直接更新，其余的放入窗口，窗口超出大小时把最旧的条目交给admit
+// AddWithExpire adds a value that expires at the given deadline
+func (c *TinyLFUCache[K, V]) AddWithExpire(key K, value V, expire time.Time) {
+	// 候选者被拒绝时主缓存没有新增条目，不会触发TypedCache的清理，所以每次Add都清理一下主缓存
+	c.main.sweep(sweepSamples)
+	if c.main.add(key, value, expire) {
+		for c.mainBytes != 0 && c.main.bytes() > c.mainBytes {
+			c.main.removeOldest()
+		}
+		return
+	}
+	c.window.AddWithExpire(key, value, expire)
+	for c.windowBytes != 0 && c.window.Bytes() > c.windowBytes {
+		candidate, _ := c.window.popOldest()
+		c.admit(candidate)
+	}
+	c.resize()
+}
+
+// admit 决定从窗口淘汰的候选者是否进入主缓存，进入时淘汰主缓存中最旧的条目腾出空间，否则丢弃候选者
+func (c *TinyLFUCache[K, V]) admit(candidate *entry[K, V]) {
+	if !c.admissible(candidate) {
+		if c.OnEvicted != nil {
+			c.OnEvicted(candidate.key, candidate.value, EvictCapacity)
+		}
+		return
+	}
+	for c.main.bytes()+candidate.size > c.mainBytes {
+		c.main.removeOldest()
+	}
+	c.main.probation.AddWithExpire(candidate.key, candidate.value, candidate.expire)
+}
+
+// admissible 主缓存有空间时直接接受候选者，否则只有候选者的访问频率高于所有将因它被淘汰的条目时才接受
+// 条目的大小不同，一个大的候选者可能需要淘汰多个条目，只和第一个比较的话，偶尔访问一次的大条目也能挤掉很多更热的小条目
+func (c *TinyLFUCache[K, V]) admissible(candidate *entry[K, V]) bool {
+	if candidate.size > c.mainBytes {
+		return false
+	}
+	need := c.main.bytes() + candidate.size - c.mainBytes
+	if need <= 0 {
+		return true
+	}
+	freq := c.frequency(candidate.key)
+	admit := true
+	c.main.eachOldest(func(victim *entry[K, V]) bool {
+		if c.frequency(victim.key) >= freq {
+			admit = false
+			return false
+		}
+		need -= victim.size
+		return need > 0
+	})
+	return admit
+}
+
+// resize 条目数超过sketch的宽度时扩大sketch和doorkeeper，已有的频率信息会被丢弃
+func (c *TinyLFUCache[K, V]) resize() {
+	if width := len(c.sketch.rows[0]); c.Len() > width {
+		c.sketch = newCountMinSketch(2 * width)
+		c.door = newDoorkeeper(16 * width)
+	}
+}
+
+// RemoveOldest 优先淘汰主缓存中最旧的条目，主缓存为空时淘汰窗口中最旧的条目
+// RemoveOldest removes the oldest item of the main cache
+func (c *TinyLFUCache[K, V]) RemoveOldest() {
+	if c.main.len() > 0 {
+		c.main.removeOldest()
+		return
+	}
+	c.window.RemoveOldest()
+}
+
+// Remove removes the provided key from the cache
+func (c *TinyLFUCache[K, V]) Remove(key K) bool {
+	return c.window.Remove(key) || c.main.probation.Remove(key) || c.main.protected.Remove(key)
+}
+
+// Range 依次遍历窗口、主缓存受保护的段和试用段，每一段中最近访问的在前
+// Range calls f for each entry of the window and then the main cache until f returns false
+func (c *TinyLFUCache[K, V]) Range(f func(key K, value V) bool) {
+	_ = c.window.each(f) && c.main.protected.each(f) && c.main.probation.each(f)
+}
+
+// Len the number of cache entries
+func (c *TinyLFUCache[K, V]) Len() int {
+	return c.window.Len() + c.main.len()
+}
+
+// Bytes returns the bytes used by keys and values
+func (c *TinyLFUCache[K, V]) Bytes() int64 {
+	return c.window.Bytes() + c.main.bytes()
+}
+
+// slru 是分段LRU：新准入的条目放入probation，在probation中再次被访问时升级到protected
+// protected超出protectedBytes时，其中最旧的条目降级回probation，淘汰总是先从probation开始
+type slru[K comparable, V any] struct {
+	protectedBytes int64
+	probation      *TypedCache[K, V]
+	protected      *TypedCache[K, V]
+}
+
+func (s *slru[K, V]) get(key K) (value V, ok bool) {
+	if value, ok = s.protected.Get(key); ok {
+		return value, ok
+	}
+	ele, ok := s.probation.cache[key]
+	if !ok {
+		return value, false
+	}
+	if ele.Value.(*entry[K, V]).expired(now()) {
+		s.probation.removeElement(ele, EvictExpired)
+		return value, false
+	}
+	kv := s.probation.unlink(ele)
+	s.promote(kv)
+	return kv.value, true
+}
+
+// promote 把条目放入protected，并把protected中超出的条目降级回probation
+func (s *slru[K, V]) promote(kv *entry[K, V]) {
+	s.protected.AddWithExpire(kv.key, kv.value, kv.expire)
+	for s.protected.Bytes() > s.protectedBytes && s.protected.Len() > 1 {
+		demoted, _ := s.protected.popOldest()
+		s.probation.AddWithExpire(demoted.key, demoted.value, demoted.expire)
+	}
+}
+
+// add 更新已经在主缓存中的key，返回key是否存在
+func (s *slru[K, V]) add(key K, value V, expire time.Time) bool {
+	if _, ok := s.protected.cache[key]; ok {
+		s.promote(&entry[K, V]{key: key, value: value, expire: expire})
+		return true
+	}
+	if _, ok := s.probation.cache[key]; ok {
+		s.probation.AddWithExpire(key, value, expire)
+		return true
+	}
+	return false
+}
+
+// oldest 返回下一个会被淘汰的条目
+func (s *slru[K, V]) oldest() (*entry[K, V], bool) {
+	if kv, ok := s.probation.oldest(); ok {
+		return kv, ok
+	}
+	return s.protected.oldest()
+}
+
+// eachOldest 按淘汰的顺序对主缓存中的条目调用f，直到f返回false
+func (s *slru[K, V]) eachOldest(f func(kv *entry[K, V]) bool) {
+	for _, c := range []*TypedCache[K, V]{s.probation, s.protected} {
+		for ele := c.ll.Back(); ele != nil; ele = ele.Prev() {
+			if !f(ele.Value.(*entry[K, V])) {
+				return
+			}
+		}
+	}
+}
+
+// sweep 分别清理试用段和受保护的段中最旧的最多n个过期条目
+func (s *slru[K, V]) sweep(n int) {
+	s.probation.sweep(n)
+	s.protected.sweep(n)
+}
+
+func (s *slru[K, V]) removeOldest() {
+	if s.probation.Len() > 0 {
+		s.probation.RemoveOldest()
+		return
+	}
+	s.protected.RemoveOldest()
+}
+
+func (s *slru[K, V]) len() int {
+	return s.probation.Len() + s.protected.Len()
+}
+
+func (s *slru[K, V]) bytes() int64 {
+	return s.probation.Bytes() + s.protected.Bytes()
+}
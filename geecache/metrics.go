@@ -0,0 +1,155 @@
+package geecache
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets 是远程节点请求耗时直方图的桶上界，单位为秒
+var latencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram 是一个简单的无锁直方图，counts[i]记录落在第i个桶（不累加）的次数，最后一个桶对应+Inf
+type histogram struct {
+	counts []atomic.Int64
+	sum    atomic.Int64 // nanoseconds
+	count  atomic.Int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]atomic.Int64, len(latencyBuckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.SearchFloat64s(latencyBuckets, d.Seconds())
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+	h.count.Add(1)
+}
+
+// MetricsHandler 返回一个http.Handler，以Prometheus文本格式输出所有Group的统计数据，以及本节点访问各个远程节点的耗时直方图
+// 它需要和HTTPPool一起挂载到同一个http.ServeMux上，例如mux.Handle("/metrics", pool.MetricsHandler())
+// MetricsHandler renders group and peer metrics in the Prometheus text exposition format
+func (p *HTTPPool) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		writeGroupMetrics(bw, allGroups())
+		p.writePeerMetrics(bw)
+		if err := bw.Flush(); err != nil {
+			p.Log("write metrics: %v", err)
+		}
+	})
+}
+
+// metricFamily 对应Prometheus中的一个指标，value从Group中取出对应的值
+type metricFamily struct {
+	name  string
+	help  string
+	typ   string
+	value func(s Stats) int64
+}
+
+var groupFamilies = []metricFamily{
+	{"geecache_gets_total", "Get requests, including requests from peers.", "counter", func(s Stats) int64 { return s.Gets }},
+	{"geecache_hits_total", "Get requests served from mainCache or hotCache.", "counter", func(s Stats) int64 { return s.CacheHits }},
+	{"geecache_misses_total", "Get requests that missed both caches.", "counter", func(s Stats) int64 { return s.Gets - s.CacheHits }},
+	{"geecache_hot_cache_hits_total", "Get requests served from hotCache.", "counter", func(s Stats) int64 { return s.HotCacheHits }},
+	{"geecache_loads_total", "Cache misses that needed a load.", "counter", func(s Stats) int64 { return s.Loads }},
+	{"geecache_loads_deduped_total", "Loads actually executed after singleflight.", "counter", func(s Stats) int64 { return s.LoadsDeduped }},
+	{"geecache_peer_loads_total", "Successful loads from remote peers.", "counter", func(s Stats) int64 { return s.PeerLoads }},
+	{"geecache_peer_errors_total", "Failed loads from remote peers.", "counter", func(s Stats) int64 { return s.PeerErrors }},
+	{"geecache_local_loads_total", "Successful loads from the Getter.", "counter", func(s Stats) int64 { return s.LocalLoads }},
+	{"geecache_local_load_errors_total", "Failed loads from the Getter.", "counter", func(s Stats) int64 { return s.LocalLoadErrs }},
+	{"geecache_server_requests_total", "Requests received from remote peers.", "counter", func(s Stats) int64 { return s.ServerRequests }},
+}
+
+// cacheFamilies 是每个cache（main或hot）的指标
+var cacheFamilies = []struct {
+	name  string
+	help  string
+	typ   string
+	value func(s CacheStats, c *cache) int64
+}{
+	{"geecache_cache_bytes", "Bytes used by keys and values.", "gauge", func(s CacheStats, c *cache) int64 { return s.Bytes }},
+	{"geecache_cache_max_bytes", "Byte budget of the cache, 0 means unlimited.", "gauge", func(s CacheStats, c *cache) int64 { return c.maxBytes() }},
+	{"geecache_cache_items", "Number of entries in the cache.", "gauge", func(s CacheStats, c *cache) int64 { return s.Items }},
+	{"geecache_cache_gets_total", "Lookups in the cache.", "counter", func(s CacheStats, c *cache) int64 { return s.Gets }},
+	{"geecache_cache_hits_total", "Lookups that hit the cache.", "counter", func(s CacheStats, c *cache) int64 { return s.Hits }},
+	{"geecache_cache_evictions_total", "Entries evicted by capacity or expiry.", "counter", func(s CacheStats, c *cache) int64 { return s.Evictions }},
+}
+
+func writeGroupMetrics(w *bufio.Writer, groups []*Group) {
+	stats := make([]Stats, len(groups))
+	for i, g := range groups {
+		stats[i] = g.Stats()
+	}
+	for _, f := range groupFamilies {
+		writeHeader(w, f.name, f.help, f.typ)
+		for i, g := range groups {
+			fmt.Fprintf(w, "%s{group=\"%s\"} %d\n", f.name, escapeLabel(g.name), f.value(stats[i]))
+		}
+	}
+
+	type cacheRef struct {
+		group string
+		name  string
+		c     *cache
+		s     CacheStats
+	}
+	var caches []cacheRef
+	for _, g := range groups {
+		caches = append(caches,
+			cacheRef{g.name, "main", &g.mainCache, g.mainCache.stats()},
+			cacheRef{g.name, "hot", &g.hotCache, g.hotCache.stats()})
+	}
+	for _, f := range cacheFamilies {
+		writeHeader(w, f.name, f.help, f.typ)
+		for _, c := range caches {
+			fmt.Fprintf(w, "%s{group=\"%s\",cache=\"%s\"} %d\n", f.name, escapeLabel(c.group), c.name, f.value(c.s, c.c))
+		}
+	}
+}
+
+func (p *HTTPPool) writePeerMetrics(w *bufio.Writer) {
+	p.mu.Lock()
+	peers := make([]string, 0, len(p.httpGetters))
+	getters := make(map[string]*httpGetter, len(p.httpGetters))
+	for peer, getter := range p.httpGetters {
+		peers = append(peers, peer)
+		getters[peer] = getter
+	}
+	p.mu.Unlock()
+	sort.Strings(peers)
+
+	const name = "geecache_peer_request_duration_seconds"
+	writeHeader(w, name, "Latency of requests sent to remote peers.", "histogram")
+	for _, peer := range peers {
+		h := getters[peer].latency
+		label := escapeLabel(peer)
+		var cumulative int64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i].Load()
+			fmt.Fprintf(w, "%s_bucket{peer=\"%s\",le=\"%s\"} %d\n", name, label, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		cumulative += h.counts[len(latencyBuckets)].Load()
+		fmt.Fprintf(w, "%s_bucket{peer=\"%s\",le=\"+Inf\"} %d\n", name, label, cumulative)
+		fmt.Fprintf(w, "%s_sum{peer=\"%s\"} %s\n", name, label, strconv.FormatFloat(time.Duration(h.sum.Load()).Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{peer=\"%s\"} %d\n", name, label, h.count.Load())
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
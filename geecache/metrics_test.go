@@ -0,0 +1,42 @@
+package geecache
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	gee := NewGroup("metrics", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	gee.Get("Tom")
+	gee.Get("Tom")
+
+	pool := NewHTTPPool("http://localhost:8001")
+	pool.Set("http://localhost:8001", "http://localhost:8002")
+	pool.httpGetters["http://localhost:8002"].latency.observe(3 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	pool.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, line := range []string{
+		`geecache_gets_total{group="metrics"} 2`,
+		`geecache_hits_total{group="metrics"} 1`,
+		`geecache_misses_total{group="metrics"} 1`,
+		`geecache_cache_items{group="metrics",cache="main"} 1`,
+		fmt.Sprintf(`geecache_cache_max_bytes{group="metrics",cache="hot"} %d`, (2<<10)/hotCacheRatio),
+		`geecache_peer_request_duration_seconds_bucket{peer="http://localhost:8002",le="0.0025"} 0`,
+		`geecache_peer_request_duration_seconds_bucket{peer="http://localhost:8002",le="0.005"} 1`,
+		`geecache_peer_request_duration_seconds_count{peer="http://localhost:8002"} 1`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("metrics output missing %q", line)
+		}
+	}
+}
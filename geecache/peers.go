@@ -1,5 +1,10 @@
 package geecache
 
+import (
+	pb "LinJz_gee_cache/geecache/geecachepb"
+	"context"
+)
+
 // 在这里，抽象出两个接口
 
 // PeerPicker 的PickPeer()方法用于根据传入的key选择相应节点的PeerGetter，PeerGetter就对应于上述流程中的HTTP客户端。
@@ -8,8 +13,54 @@ type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
 }
 
-// PeerGetter 的Get()方法用于从对应的group查找缓存值。
+// PeerListPicker 是可选的接口，PickPeers()按照哈希环上的顺序返回最多n个远程节点，第一个是key的所属节点，后面是它的后继节点
+// 遇到本节点时停止，因为轮到本节点时应该从本地加载，所以当key属于本节点时返回空
+// PeerListPicker is implemented by pickers that can list the owner of a key followed by its successors
+type PeerListPicker interface {
+	PeerPicker
+	PickPeers(key string, n int) []PeerGetter
+}
+
+// OwnerPicker 是可选的接口，PickOwners()只按哈希环返回key的所属节点及其后继节点，最多n个，遇到本节点时停止
+// 与PickPeers不同，它不会跳过熔断中或者负载达到上限的节点，用于删除缓存这类必须通知到真正所属节点的操作
+// OwnerPicker is implemented by pickers that can list the owners of a key regardless of their health or load
+type OwnerPicker interface {
+	PeerPicker
+	PickOwners(key string, n int) []PeerGetter
+}
+
+// PeerLister 是可选的接口，Peers()返回所有的远程节点
+// 从远程节点获取的值可能被放入任意节点的hotCache中，Group.Remove用它通知所有节点删除这些副本
+// PeerLister is implemented by pickers that can list all remote peers
+type PeerLister interface {
+	PeerPicker
+	Peers() []PeerGetter
+}
+
+// PeerGetter 的Get()方法用于从对应的group查找缓存值，Remove()方法用于通知远程节点删除对应的缓存值。
 // PeerGetter is the interface that must be implemented by a peer
 type PeerGetter interface {
 	Get(group string, key string) ([]byte, error)
+	Remove(ctx context.Context, group string, key string) error
+}
+
+// ContextPeerGetter 是可选的接口，实现了它的PeerGetter可以把ctx传递给发往远程节点的请求，用来取消请求或者限制超时时间
+// ContextPeerGetter is implemented by peers whose Get honours a context
+type ContextPeerGetter interface {
+	PeerGetter
+	GetContext(ctx context.Context, group string, key string) ([]byte, error)
+}
+
+// ProtoPeerGetter 是可选的接口，实现了它的PeerGetter使用protobuf协议与远程节点通信，响应中除了缓存值还带有过期时间等元数据
+// ProtoPeerGetter is implemented by peers that speak the protobuf peer protocol
+type ProtoPeerGetter interface {
+	PeerGetter
+	GetProto(ctx context.Context, in *pb.GetRequest, out *pb.GetResponse) error
+}
+
+// BatchPeerGetter 是可选的接口，实现了它的PeerGetter可以在一次请求中查询多个key，结果按keys的顺序返回，单个key失败时对应结果的Error不为空
+// BatchPeerGetter is implemented by peers that can fetch many keys in one stream
+type BatchPeerGetter interface {
+	PeerGetter
+	GetBatch(ctx context.Context, group string, keys []string) ([]*pb.GetResponse, error)
 }
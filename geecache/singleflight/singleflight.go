@@ -1,16 +1,24 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // 在一瞬间有大量请求get(key)，而且key未被缓存或者未被缓存在当前节点 如果不用singleflight，那么这些请求都会发送远端节点或者从本地数据库读取，会造成远端节点或本地数据库压力猛增。
 // 使用singleflight，第一个get(key)请求到来时，singleflight会记录当前key正在被处理，后续的请求只需要等待第一个请求处理完成，取返回值即可。
 // 并发场景下如果 GeeCache 已经向其他节点/源获取数据了，那么就加锁阻塞其他相同的请求，等待请求结果，防止其他节点/源压力猛增被击穿。
 
 // call 代表正在进行中，或已经结束的请求。使用sync.WaitGroup锁避免重入
+// done 在请求结束时被关闭，DoContext用它和ctx.Done()一起select，从而可以提前放弃等待
+// waiters 是还在等待结果的调用者个数，cancel 取消DoContext传给fn的ctx，都由Group.mu保护
 type call struct {
-	wg  sync.WaitGroup
-	val any
-	err error
+	wg      sync.WaitGroup
+	done    chan struct{}
+	val     any
+	err     error
+	waiters int
+	cancel  context.CancelCauseFunc
 }
 
 // Group 是singleflight的主数据结构，管理不同key的请求(call)
@@ -32,23 +40,71 @@ func (g *Group) Do(key string, fn func() (any, error)) (any, error) {
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
+		c.waiters++ // Do不会放弃等待，所以不需要减回去
 		g.mu.Unlock()
 		c.wg.Wait()         // 如果请求正在进行中，则等待
 		return c.val, c.err // 请求结束，返回结果
 	}
-	c := new(call)
+	c := &call{done: make(chan struct{}), waiters: 1}
 	c.wg.Add(1)  // 发起请求前加锁
 	g.m[key] = c // 添加到g.m表明key已经有对应的请求在处理
 	g.mu.Unlock()
 
-	c.val, c.err = fn() // 调用fn，发起请求
-	c.wg.Done()         // 请求结束，释放锁
+	g.doCall(c, key, fn)
+	return c.val, c.err // 返回结果
+}
 
+// DoContext 与Do相同，但每个调用者都可以通过ctx提前返回，返回值为ctx.Err()
+// fn在单独的协程中执行，收到的ctx带有第一个调用者ctx中的值，但没有截止时间，也不会随第一个调用者取消或超时，
+// 这样第一个调用者放弃之后，其他调用者仍然可以拿到结果；只有所有调用者都放弃等待时，fn的ctx才会被取消，
+// 取消的原因context.Cause(ctx)是最后一个放弃的调用者的ctx.Err()，fn可以据此区分调用者超时（context.DeadlineExceeded）和调用者主动取消（context.Canceled）
+// DoContext is like Do but callers give up when their ctx is done, fn is canceled once every caller has given up
+func (g *Group) DoContext(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error) {
 	g.mu.Lock()
-	delete(g.m, key) // 更新g.m，为什么在请求后要删除g.m映射关系中的key，详细见下方
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	c, ok := g.m[key]
+	if !ok {
+		fctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		c.wg.Add(1)
+		g.m[key] = c
+		go g.doCall(c, key, func() (any, error) { return fn(fctx) })
+	}
+	c.waiters++
 	g.mu.Unlock()
 
-	return c.val, c.err // 返回结果
+	select {
+	case <-c.done: // 请求结束，返回结果
+		return c.val, c.err
+	case <-ctx.Done(): // 调用者不愿意再等了
+		g.mu.Lock()
+		if c.waiters--; c.waiters == 0 && c.cancel != nil {
+			c.cancel(ctx.Err())
+			if g.m[key] == c { // 之后的调用者重新发起请求，而不是等待一个已经取消的请求
+				delete(g.m, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// doCall 执行fn，并唤醒所有等待该请求的调用者
+func (g *Group) doCall(c *call, key string, fn func() (any, error)) {
+	c.val, c.err = fn() // 调用fn，发起请求
+	close(c.done)
+	c.wg.Done() // 请求结束，释放锁
+
+	g.mu.Lock()
+	if c.cancel != nil {
+		c.cancel(nil) // 释放fn的ctx
+	}
+	if g.m[key] == c {
+		delete(g.m, key) // 更新g.m，为什么在请求后要删除g.m映射关系中的key，详细见下方
+	}
+	g.mu.Unlock()
 }
 
 // 能否在请求结束后 不删除g.m映射关系中的key？
@@ -0,0 +1,136 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	var g Group
+	v, err := g.Do("key", func() (any, error) {
+		return "bar", nil
+	})
+	if v.(string) != "bar" || err != nil {
+		t.Errorf("Do v = %v, error = %v", v, err)
+	}
+}
+
+// 测试并发调用同一个key时，fn只会被调用一次
+func TestDoDedup(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (any, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "bar", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := g.Do("key", fn); err != nil || v.(string) != "bar" {
+				t.Errorf("Do v = %v, error = %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // 让所有协程都进入等待
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn called %d times, expect 1", n)
+	}
+}
+
+// 测试等待中的调用者在ctx超时后提前返回
+func TestDoContextCancel(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	go g.Do("key", func() (any, error) {
+		<-release
+		return "bar", nil
+	})
+	time.Sleep(10 * time.Millisecond) // 确保第一个请求已经开始
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.DoContext(ctx, "key", func(context.Context) (any, error) {
+		return "baz", nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DoContext error = %v, expect %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+}
+
+// 测试第一个调用者取消后，其他调用者仍然能拿到结果
+func TestDoContextLeaderCancel(t *testing.T) {
+	var g Group
+	started, release := make(chan struct{}), make(chan struct{})
+	fn := func(ctx context.Context) (any, error) {
+		close(started)
+		select {
+		case <-release:
+			return "bar", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := g.DoContext(leaderCtx, "key", fn)
+		leader <- err
+	}()
+	<-started
+	follower := make(chan any)
+	go func() {
+		v, _ := g.DoContext(context.Background(), "key", fn)
+		follower <- v
+	}()
+	for { // 等待follower加入
+		g.mu.Lock()
+		n := g.m["key"].waiters
+		g.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader error = %v, expect %v", err, context.Canceled)
+	}
+	close(release)
+	if v := <-follower; v != "bar" {
+		t.Errorf("follower got %v, expect bar", v)
+	}
+}
+
+// 测试所有调用者都放弃等待后，fn的ctx被取消
+func TestDoContextAllCancel(t *testing.T) {
+	var g Group
+	canceled := make(chan error, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	g.DoContext(ctx, "key", func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		canceled <- context.Cause(ctx)
+		return nil, ctx.Err()
+	})
+	select {
+	case cause := <-canceled:
+		if cause != context.DeadlineExceeded { // 调用者是因为超时放弃的
+			t.Fatalf("fn ctx canceled with cause %v, expect %v", cause, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fn ctx was not canceled after every caller gave up")
+	}
+}
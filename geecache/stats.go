@@ -0,0 +1,127 @@
+package geecache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats 是Group统计数据的快照
+// Stats are per-group statistics
+type Stats struct {
+	Gets           int64 // 所有的Get请求，包括来自远程节点的请求
+	CacheHits      int64 // 命中mainCache或hotCache的次数
+	HotCacheHits   int64 // 命中hotCache的次数
+	Loads          int64 // 没有命中缓存，需要加载的次数（包括被singleflight合并的请求）
+	LoadsDeduped   int64 // 经过singleflight合并之后，真正执行加载的次数
+	PeerLoads      int64 // 从远程节点加载成功的次数
+	PeerErrors     int64 // 从远程节点加载失败的次数
+	LocalLoads     int64 // 通过回调函数加载成功的次数
+	LocalLoadErrs  int64 // 通过回调函数加载失败的次数
+	ServerRequests int64 // 收到的来自远程节点的请求数
+}
+
+// groupStats 使用原子计数器保存统计数据，可以在并发场景下无锁更新
+type groupStats struct {
+	gets           atomic.Int64
+	cacheHits      atomic.Int64
+	hotCacheHits   atomic.Int64
+	loads          atomic.Int64
+	loadsDeduped   atomic.Int64
+	peerLoads      atomic.Int64
+	peerErrors     atomic.Int64
+	localLoads     atomic.Int64
+	localLoadErrs  atomic.Int64
+	serverRequests atomic.Int64
+	minuteQPS      rateCounter // 最近一分钟内来自远程节点的请求速率
+}
+
+// serverRequest 记录一次来自远程节点的请求
+func (s *groupStats) serverRequest() {
+	s.serverRequests.Add(1)
+	s.minuteQPS.incr()
+}
+
+// rateCounter 按一分钟的窗口统计事件发生的速率，rate返回上一个完整窗口的每秒次数，第一个窗口结束前返回当前窗口的速率
+type rateCounter struct {
+	mu    sync.Mutex
+	start time.Time
+	n     int64
+	last  float64
+	full  bool // 是否已经有一个完整的窗口
+}
+
+func (c *rateCounter) incr() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(time.Now())
+	c.n++
+}
+
+func (c *rateCounter) rate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.roll(now)
+	if c.full {
+		return c.last
+	}
+	if elapsed := now.Sub(c.start).Seconds(); elapsed >= 1 {
+		return float64(c.n) / elapsed
+	}
+	return float64(c.n)
+}
+
+// roll 当前窗口满一分钟后，记录它的速率并开始新的窗口
+func (c *rateCounter) roll(now time.Time) {
+	if c.start.IsZero() {
+		c.start = now
+	}
+	if elapsed := now.Sub(c.start); elapsed >= time.Minute {
+		c.last = float64(c.n) / elapsed.Seconds()
+		c.full = true
+		c.start = now
+		c.n = 0
+	}
+}
+
+// Stats 返回Group当前统计数据的快照
+// Stats returns a snapshot of the group's statistics
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:           g.stats.gets.Load(),
+		CacheHits:      g.stats.cacheHits.Load(),
+		HotCacheHits:   g.stats.hotCacheHits.Load(),
+		Loads:          g.stats.loads.Load(),
+		LoadsDeduped:   g.stats.loadsDeduped.Load(),
+		PeerLoads:      g.stats.peerLoads.Load(),
+		PeerErrors:     g.stats.peerErrors.Load(),
+		LocalLoads:     g.stats.localLoads.Load(),
+		LocalLoadErrs:  g.stats.localLoadErrs.Load(),
+		ServerRequests: g.stats.serverRequests.Load(),
+	}
+}
+
+// CacheType 表示Group中的某一个cache
+// CacheType represents a type of cache
+type CacheType int
+
+const (
+	// MainCache 存放本节点负责的key
+	MainCache CacheType = iota + 1
+	// HotCache 存放属于远程节点的热点key
+	HotCache
+)
+
+// CacheStats 返回指定cache统计数据的快照
+// CacheStats returns stats about the provided cache within the group
+func (g *Group) CacheStats(which CacheType) CacheStats {
+	switch which {
+	case MainCache:
+		return g.mainCache.stats()
+	case HotCache:
+		return g.hotCache.stats()
+	default:
+		return CacheStats{}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sort"
 )
 
 // 同样地，我们使用map模拟了数据源db
@@ -30,15 +32,18 @@ func startCacheServer(addr string, addrs []string, gee *geecache.Group) {
 	peers := geecache.NewHTTPPool(addr)
 	peers.Set(addrs...)
 	gee.RegisterPeers(peers)
+	mux := http.NewServeMux()
+	mux.Handle("/_geecache/", peers)
+	mux.Handle("/metrics", peers.MetricsHandler())
 	log.Println("geeCache is running at", addr)
-	log.Fatal(http.ListenAndServe(addr[7:], peers))
+	log.Fatal(http.ListenAndServe(addr[7:], mux))
 }
 
 func startAPIServer(apiAddr string, gee *geecache.Group) {
 	http.Handle("/api", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			key := r.URL.Query().Get("key")
-			view, err := gee.Get(key)
+			view, err := gee.GetContext(r.Context(), key)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -58,7 +63,15 @@ func startAPIServer(apiAddr string, gee *geecache.Group) {
 // startCacheServer()用来启动缓存服务器，创建HTTPPool，添加节点信息，注册到gee，启动HTTP服务（共3个端口，8001/8002/8003），用户不感知
 // startAPIServer()用来启动一个API服务(端口9999)，与用户进行交互，用户感知
 // main()函数需要命令行传入port和api2个参数，用来在指定端口启动HTTP服务（为了方便，我们将启动的命令封装为一个shell脚本）
+// 第一个参数为ring时运行ring子命令，打印哈希环的分布情况，见ring.go
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "ring" {
+		if err := runRing(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	var port int
 	var api bool
 	flag.IntVar(&port, "port", 8001, "Geecache server port")
@@ -76,6 +89,7 @@ func main() {
 	for _, v := range addrMap {
 		addrs = append(addrs, v)
 	}
+	sort.Strings(addrs) // map的遍历顺序是随机的，所有节点按相同的顺序设置peers
 
 	gee := createGroup() // 共用同一个group
 	if api {
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"LinJz_gee_cache/geecache/consistenthash"
+	"bufio"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// hashFns 是ring子命令可以选择的哈希函数
+var hashFns = map[string]consistenthash.Hash{
+	"crc32": crc32.ChecksumIEEE,
+	"crc32c": func(data []byte) uint32 {
+		return crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))
+	},
+	"fnv1a": func(data []byte) uint32 {
+		h := fnv.New32a()
+		h.Write(data)
+		return h.Sum32()
+	},
+}
+
+// runRing 实现了ring子命令，打印给定节点、虚拟节点倍数和哈希函数下每个节点在哈希环上的份额
+// 如果通过-keys指定了样本key文件（每行一个key），还会统计每个节点实际分到的key的个数，用来选择合适的虚拟节点倍数
+// 例如：geecache ring -nodes http://localhost:8001,http://localhost:8002,http://localhost:8003 -replicas 50 -keys keys.txt
+func runRing(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("ring", flag.ContinueOnError)
+	nodes := fs.String("nodes", "http://localhost:8001,http://localhost:8002,http://localhost:8003", "Comma separated list of nodes")
+	replicas := fs.Int("replicas", 50, "Number of virtual nodes per node")
+	hashName := fs.String("hash", "crc32", "Hash function: "+strings.Join(hashNames(), ", "))
+	keyFile := fs.String("keys", "", "File of sample keys, one per line")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	fn, ok := hashFns[*hashName]
+	if !ok {
+		return fmt.Errorf("unknown hash %q, expect one of %s", *hashName, strings.Join(hashNames(), ", "))
+	}
+
+	m := consistenthash.New(*replicas, fn)
+	for _, node := range strings.Split(*nodes, ",") {
+		if node = strings.TrimSpace(node); node != "" {
+			m.Add(node)
+		}
+	}
+	shares := m.Distribution()
+	if len(shares) == 0 {
+		return fmt.Errorf("no nodes given")
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	if *keyFile != "" {
+		f, err := os.Open(*keyFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if key := scanner.Text(); key != "" {
+				counts[m.Get(key)]++
+				total++
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return fmt.Errorf("reading %s: %v", *keyFile, err)
+		}
+	}
+
+	fmt.Fprintf(stdout, "hash=%s replicas=%d nodes=%d collisions=%d\n", *hashName, *replicas, len(shares), len(m.Diagnostics().Collisions))
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "NODE\tVNODES\tRING%\tKEYS\tKEYS%\t")
+	var sq float64
+	mean := 1 / float64(len(shares))
+	for _, s := range shares {
+		keyPct := 0.0
+		if total > 0 {
+			keyPct = float64(counts[s.Node]) / float64(total) * 100
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%d\t%.2f\t\n", s.Node, s.Replicas, s.Share*100, counts[s.Node], keyPct)
+		sq += (s.Share - mean) * (s.Share - mean)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	// 相对标准差越小，各个节点的份额越接近
+	fmt.Fprintf(stdout, "ring share relative stddev: %.2f%%\n", math.Sqrt(sq/float64(len(shares)))/mean*100)
+	return nil
+}
+
+func hashNames() []string {
+	names := make([]string, 0, len(hashFns))
+	for name := range hashFns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
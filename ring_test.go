@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunRing(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		keys    string // 不为空时写入临时文件，并通过-keys传入
+		want    string
+		wantErr string
+	}{
+		{
+			name: "crc32",
+			args: []string{"-nodes", "a, b,,c", "-replicas", "3"},
+			want: "hash=crc32 replicas=3 nodes=3 collisions=0\n" +
+				"  NODE  VNODES  RING%  KEYS  KEYS%\n" +
+				"     a       3  27.00     0   0.00\n" +
+				"     b       3  49.98     0   0.00\n" +
+				"     c       3  23.02     0   0.00\n" +
+				"ring share relative stddev: 35.64%\n",
+		},
+		{
+			name: "keys",
+			args: []string{"-nodes", "a,b,c", "-replicas", "3"},
+			keys: "Tom\nJack\nSam\n\nKate\n",
+			want: "hash=crc32 replicas=3 nodes=3 collisions=0\n" +
+				"  NODE  VNODES  RING%  KEYS  KEYS%\n" +
+				"     a       3  27.00     0   0.00\n" +
+				"     b       3  49.98     2  50.00\n" +
+				"     c       3  23.02     2  50.00\n" +
+				"ring share relative stddev: 35.64%\n",
+		},
+		{
+			name: "fnv1a",
+			args: []string{"-nodes", "a,b", "-replicas", "10", "-hash", "fnv1a"},
+			want: "hash=fnv1a replicas=10 nodes=2 collisions=0\n" +
+				"  NODE  VNODES  RING%  KEYS  KEYS%\n" +
+				"     a      10  12.11     0   0.00\n" +
+				"     b      10  87.89     0   0.00\n" +
+				"ring share relative stddev: 75.78%\n",
+		},
+		{
+			name:    "unknown hash",
+			args:    []string{"-hash", "md5"},
+			wantErr: `unknown hash "md5", expect one of crc32, crc32c, fnv1a`,
+		},
+		{
+			name:    "no nodes",
+			args:    []string{"-nodes", " , "},
+			wantErr: "no nodes given",
+		},
+		{
+			name:    "missing keys file",
+			args:    []string{"-keys", filepath.Join(t.TempDir(), "missing.txt")},
+			wantErr: "no such file or directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.keys != "" {
+				path := filepath.Join(t.TempDir(), "keys.txt")
+				if err := os.WriteFile(path, []byte(tt.keys), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-keys", path)
+			}
+			var out bytes.Buffer
+			err := runRing(args, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("runRing error = %v, expect %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Fatalf("runRing output:\n%s\nexpect:\n%s", got, tt.want)
+			}
+		})
+	}
+}